@@ -0,0 +1,271 @@
+package backtest
+
+import (
+	"math"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"trader.fun/papertrade"
+	"trader.fun/pumpfun"
+	"trader.fun/strategy"
+	"trader.fun/tape"
+)
+
+type Config struct {
+	StartingSol  float64
+	BalanceRisk  float64 // percent of sol balance per trade
+	Slippage     float64
+	MaxPositions int
+	Sample       time.Duration // equity curve resolution
+}
+
+type EquityPoint struct {
+	Time   time.Time
+	Equity float64
+}
+
+type Trade struct {
+	Mint   solana.PublicKey
+	Opened time.Time
+	Closed time.Time
+	SolIn  float64
+	SolOut float64
+	PnL    float64
+	Reason string // the strategy's sell reason, empty when it was still open at the end of the tape
+}
+
+type Result struct {
+	Equity      []EquityPoint
+	Trades      []Trade
+	Start, End  float64
+	WinRate     float64 // percent
+	AvgHold     time.Duration
+	MaxDrawdown float64 // percent
+	Sharpe      float64 // annualized from the sampled equity returns
+}
+
+type open struct {
+	coin   pumpfun.Coin
+	opened time.Time
+	entry  float64
+	solIn  float64
+}
+
+type engine struct {
+	cfg      Config
+	strategy strategy.Strategy
+	paper    *papertrade.Wallet
+	curves   map[solana.PublicKey]*pumpfun.BondingCurve
+	open     map[solana.PublicKey]*open
+	result   *Result
+	now      time.Time
+	nextMark time.Time
+}
+
+// Run replays the tapes under path through strategy, entirely offline
+func Run(path string, s strategy.Strategy, cfg Config) (*Result, error) {
+	if cfg.Sample == 0 {
+		cfg.Sample = time.Minute
+	}
+	if cfg.MaxPositions == 0 {
+		cfg.MaxPositions = 1
+	}
+
+	e := &engine{
+		cfg:      cfg,
+		strategy: s,
+		paper:    papertrade.New(cfg.StartingSol),
+		curves:   make(map[solana.PublicKey]*pumpfun.BondingCurve),
+		open:     make(map[solana.PublicKey]*open),
+		result:   &Result{Start: cfg.StartingSol},
+	}
+
+	if err := tape.Replay(path, e.onEvent); err != nil {
+		return nil, err
+	}
+
+	for mint := range e.open {
+		e.close(mint, "")
+	}
+	e.mark(e.now)
+	e.result.End = e.paper.SolBalance()
+	e.result.summarize(cfg.Sample)
+
+	return e.result, nil
+}
+
+func (e *engine) onEvent(ev *tape.Event) error {
+	if e.nextMark.IsZero() {
+		e.nextMark = ev.Time
+	}
+	// nothing changed between the last event and this one, every sample that fell in between is the equity as of the last
+	for e.nextMark.Before(ev.Time) {
+		e.mark(e.nextMark)
+		e.nextMark = e.nextMark.Add(e.cfg.Sample)
+	}
+
+	e.now = ev.Time
+	if clock, ok := e.strategy.(strategy.Clock); ok {
+		clock.SetTime(ev.Time)
+	}
+
+	if pair := ev.Pair; pair != nil {
+		e.execute(e.strategy.OnNewPair(pair))
+	}
+
+	if trade := ev.Trade; trade != nil && len(trade.Mint) > 0 && trade.VTokensInBondingCurve > 0 {
+		if mint, err := solana.PublicKeyFromBase58(trade.Mint); err == nil {
+			e.curves[mint] = pumpfun.NewBondingCurveFromReserves(trade.VTokensInBondingCurve, trade.VSolInBondingCurve)
+			e.execute(e.strategy.OnTrade(trade))
+		}
+	}
+
+	if snap := ev.Snapshot; snap != nil {
+		if mint, err := solana.PublicKeyFromBase58(snap.Mint); err == nil {
+			curve := snap.Curve
+			e.curves[mint] = &curve
+		}
+	}
+
+	// the tape has no clock of its own, every event is a tick
+	e.execute(e.strategy.OnTick(e.now, e.positions()))
+
+	return nil
+}
+
+func (e *engine) positions() []strategy.Position {
+	positions := make([]strategy.Position, 0, len(e.open))
+	for mint, pos := range e.open {
+		positions = append(positions, strategy.Position{
+			Coin:   pos.coin,
+			Amount: float64(e.paper.Positions[mint].Tokens) / math.Pow10(pumpfun.TokenDecimals),
+			Entry:  pos.entry,
+			Price:  e.curves[mint].SpotPrice(),
+			Opened: pos.opened,
+		})
+	}
+	return positions
+}
+
+func (e *engine) execute(intents []strategy.Intent) {
+	for _, intent := range intents {
+		if intent.Confirm != nil && !intent.Confirm() {
+			continue
+		}
+		mint := intent.Coin.MintAddr
+		_, holding := e.open[mint]
+		switch {
+		case intent.Side == strategy.Buy && !holding && len(e.open) < e.cfg.MaxPositions:
+			e.buy(intent)
+		case intent.Side == strategy.Sell && holding:
+			// partial exits aren't simulated, any sell closes the position
+			e.close(mint, intent.Reason)
+		}
+	}
+}
+
+func (e *engine) buy(intent strategy.Intent) {
+	coin := intent.Coin
+	curve, ok := e.curves[coin.MintAddr]
+	if !ok {
+		return
+	}
+
+	solAmount := intent.Sol
+	if solAmount == 0 {
+		solAmount = e.paper.SolBalance() * (e.cfg.BalanceRisk / 100)
+	}
+
+	fill, err := e.paper.Buy(&coin, curve, solAmount, e.cfg.Slippage)
+	if err != nil || fill.Tokens == 0 {
+		return
+	}
+
+	solIn := float64(fill.Lamports) / float64(solana.LAMPORTS_PER_SOL)
+	entry := solIn / (float64(fill.Tokens) / math.Pow10(pumpfun.TokenDecimals))
+	e.open[coin.MintAddr] = &open{coin: coin, opened: e.now, entry: entry, solIn: solIn}
+}
+
+func (e *engine) close(mint solana.PublicKey, reason string) {
+	pos := e.open[mint]
+	fill, err := e.paper.Sell(&pos.coin, e.curves[mint], 100, e.cfg.Slippage)
+
+	var solOut float64
+	if err == nil {
+		solOut = float64(fill.Lamports) / float64(solana.LAMPORTS_PER_SOL)
+	} else {
+		// a graduated curve can't be sold into on pump.fun, count it as lost
+		delete(e.paper.Positions, mint)
+	}
+
+	delete(e.open, mint)
+	e.result.Trades = append(e.result.Trades, Trade{
+		Mint:   mint,
+		Opened: pos.opened,
+		Closed: e.now,
+		SolIn:  pos.solIn,
+		SolOut: solOut,
+		PnL:    solOut - pos.solIn,
+		Reason: reason,
+	})
+}
+
+// mark records the current equity stamped with at
+func (e *engine) mark(at time.Time) {
+	if at.IsZero() {
+		return
+	}
+	e.result.Equity = append(e.result.Equity, EquityPoint{Time: at, Equity: e.paper.Equity(e.curves)})
+}
+
+func (r *Result) summarize(sample time.Duration) {
+	var wins int
+	var held time.Duration
+	for _, t := range r.Trades {
+		if t.PnL > 0 {
+			wins++
+		}
+		held += t.Closed.Sub(t.Opened)
+	}
+	if len(r.Trades) > 0 {
+		r.WinRate = float64(wins) / float64(len(r.Trades)) * 100
+		r.AvgHold = held / time.Duration(len(r.Trades))
+	}
+
+	peak := r.Start
+	var returns []float64
+	for i, p := range r.Equity {
+		peak = max(peak, p.Equity)
+		if peak > 0 {
+			r.MaxDrawdown = max(r.MaxDrawdown, (peak-p.Equity)/peak*100)
+		}
+		if i > 0 && r.Equity[i-1].Equity > 0 {
+			returns = append(returns, p.Equity/r.Equity[i-1].Equity-1)
+		}
+	}
+
+	r.Sharpe = sharpe(returns, float64(365*24*time.Hour)/float64(sample))
+}
+
+func sharpe(returns []float64, periodsPerYear float64) float64 {
+	if len(returns) < 2 {
+		return 0
+	}
+
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	sd := math.Sqrt(variance / float64(len(returns)-1))
+	if sd == 0 {
+		return 0
+	}
+
+	return mean / sd * math.Sqrt(periodsPerYear)
+}
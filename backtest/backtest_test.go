@@ -0,0 +1,49 @@
+package backtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/codingsandmore/pumpfun-portal/portal"
+	"github.com/gagliardetto/solana-go"
+	"trader.fun/papertrade"
+	"trader.fun/pumpfun"
+	"trader.fun/strategy"
+	"trader.fun/tape"
+)
+
+type idle struct{}
+
+func (idle) Name() string                                                          { return "idle" }
+func (idle) OnNewPair(p *portal.NewPairResponse) []strategy.Intent                 { return nil }
+func (idle) OnTrade(p *portal.NewTradeResponse) []strategy.Intent                  { return nil }
+func (idle) OnTick(now time.Time, positions []strategy.Position) []strategy.Intent { return nil }
+
+// samples that fall in a gap between events each get their own time
+func TestEquitySamples(t *testing.T) {
+	e := &engine{
+		cfg:      Config{Sample: time.Minute},
+		strategy: idle{},
+		paper:    papertrade.New(1),
+		curves:   make(map[solana.PublicKey]*pumpfun.BondingCurve),
+		open:     make(map[solana.PublicKey]*open),
+		result:   &Result{Start: 1},
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, at := range []time.Duration{0, 30 * time.Second, 5 * time.Minute, 5*time.Minute + time.Second} {
+		if err := e.onEvent(&tape.Event{Time: start.Add(at)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []time.Duration{0, time.Minute, 2 * time.Minute, 3 * time.Minute, 4 * time.Minute, 5 * time.Minute}
+	if len(e.result.Equity) != len(want) {
+		t.Fatalf("%d equity points, want %d: %v", len(e.result.Equity), len(want), e.result.Equity)
+	}
+	for i, at := range want {
+		if got := e.result.Equity[i].Time; !got.Equal(start.Add(at)) {
+			t.Errorf("point %d at %s, want %s", i, got.Sub(start), at)
+		}
+	}
+}
@@ -2,75 +2,266 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+	"trader.fun/keystore"
 )
 
+// every field can be overridden by the environment variable TRADER_<env tag>
 type Config struct {
-	TraderPVTK    string  `json:"traderPvtK"`
-	TraderPUBK    string  `json:"traderPubK"`
-	RPCEndpoint   string  `json:"rpcEndpoint"`
-	TotalStopLoss float64 `json:"totalStopLoss"`
-	TradeStopLoss float64 `json:"tradeStopLoss"`
-	BalanceRisk   float64 `json:"balanceRisk"`
-	Traders       int     `json:"traders"`
-	Slippage      float64 `json:"slippage"`
+	TraderPVTK    string  `json:"traderPvtK,omitempty" env:"PVTK"` // legacy plaintext key, `trader keys migrate` moves it into the keystore
+	Keystore      string  `json:"keystore" env:"KEYSTORE"`
+	RPCEndpoint   string  `json:"rpcEndpoint" env:"RPC_ENDPOINT"`
+	WSEndpoint    string  `json:"wsEndpoint,omitempty" env:"WS_ENDPOINT"` // the rpc endpoint with a ws(s) scheme when empty, required when the websocket is on another port or path
+	TotalStopLoss float64 `json:"totalStopLoss" env:"TOTAL_STOP_LOSS"`
+	TradeStopLoss float64 `json:"tradeStopLoss" env:"TRADE_STOP_LOSS"`
+	BalanceRisk   float64 `json:"balanceRisk" env:"BALANCE_RISK"`
+	Traders       int     `json:"traders" env:"TRADERS"`
+	MaxPositions  int     `json:"maxPositions" env:"MAX_POSITIONS"` // per trader, backtests run one trader
+	Slippage      float64 `json:"slippage" env:"SLIPPAGE"`
+	TakeProfit    float64 `json:"takeProfit" env:"TAKE_PROFIT"`
+	TrailingStop  float64 `json:"trailingStop" env:"TRAILING_STOP"`
+	MaxHoldTime   int     `json:"maxHoldTime" env:"MAX_HOLD_TIME"` // seconds
+
+	PriorityFeePercentile float64 `json:"priorityFeePercentile" env:"PRIORITY_FEE_PERCENTILE"` // of recent fees paid against the same accounts
+	MaxPriorityFee        float64 `json:"maxPriorityFee" env:"MAX_PRIORITY_FEE"`               // sol per transaction
+	ComputeHeadroom       float64 `json:"computeHeadroom" env:"COMPUTE_HEADROOM"`              // percent over the simulated compute units
+
+	Files    []string `json:"-"` // the files this config was merged from, base first
+	Warnings []string `json:"-"` // problems that don't stop the config from loading
 }
 
-var (
-	defaultWallet  = solana.NewWallet()
-	configFileName = "config.json"
-	defaultConfig  = Config{
-		TraderPVTK:    defaultWallet.PrivateKey.String(),
-		TraderPUBK:    defaultWallet.PublicKey().String(),
-		RPCEndpoint:   rpc.MainNetBeta_RPC,
-		BalanceRisk:   4.0,  // 4%
-		TradeStopLoss: 20.0, // 20%
-		TotalStopLoss: 10.0, // 10%
-		Traders:       1,
-		Slippage:      0.04, // 4%
-	}
+const (
+	// FileName is the config loaded when none is given
+	FileName = "config.json"
+	// EnvPrefix starts every override variable, e.g. TRADER_SLIPPAGE=0.02
+	EnvPrefix = "TRADER_"
+	// MaxTraders bounds the pool, every trader polls the rpc
+	MaxTraders = 64
 )
 
-func LoadConfig() *Config {
-	if _, err := os.Stat(configFileName); os.IsNotExist(err) {
-		file, err := os.Create(configFileName)
-		if err != nil {
-			fmt.Println("Error creating config file:", err)
-			os.Exit(1)
+var defaultConfig = Config{
+	Keystore:      keystore.FileName,
+	RPCEndpoint:   rpc.MainNetBeta_RPC,
+	BalanceRisk:   4.0,  // 4%
+	TradeStopLoss: 20.0, // 20%
+	TotalStopLoss: 10.0, // 10%
+	Traders:       1,
+	MaxPositions:  1,
+	Slippage:      0.04, // 4%
+	TakeProfit:    50.0, // 50%
+	TrailingStop:  15.0, // 15% off the peak
+	MaxHoldTime:   300,  // 5 minutes
+
+	PriorityFeePercentile: 75,
+	MaxPriorityFee:        0.001,
+	ComputeHeadroom:       20.0, // 20%
+}
+
+// Load builds the config from the defaults, the base file at path, the profile overlay next to it
+// (config.paper.json for profile paper) and TRADER_* environment variables, in that order.
+// A missing base file is created with the defaults, a missing overlay is skipped.
+func Load(path, profile string) (*Config, error) {
+	config := defaultConfig
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := config.Save(path); err != nil {
+			return nil, err
 		}
-		defer file.Close()
+	}
+	if err := config.merge(path); err != nil {
+		return nil, err
+	}
 
-		configData, err := json.MarshalIndent(defaultConfig, "", "  ")
-		if err != nil {
-			fmt.Println("Error marshalling default config:", err)
-			os.Exit(1)
+	if len(profile) > 0 {
+		overlay := OverlayPath(path, profile)
+		if _, err := os.Stat(overlay); err == nil {
+			if err := config.merge(overlay); err != nil {
+				return nil, err
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("error reading config overlay: %v", err)
 		}
-		file.Write(configData)
+	}
 
-		return &defaultConfig
+	if err := config.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
 
-	return loadConfigFromFile()
+	return &config, nil
 }
 
-func loadConfigFromFile() *Config {
-	data, err := ioutil.ReadFile(configFileName)
+// OverlayPath is the profile overlay of the base config at path, config.json and live give config.live.json
+func OverlayPath(path, profile string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + profile + ext
+}
+
+// merge decodes the file at path over the config, fields it doesn't set keep their value
+func (c *Config) merge(path string) error {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		fmt.Println("Error reading config file:", err)
-		os.Exit(1)
+		return fmt.Errorf("error reading config file: %v", err)
 	}
 
-	var config Config
-	err = json.Unmarshal(data, &config)
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("error decoding %s: %v", path, err)
+	}
+	known := jsonFields()
+	var unknown []string
+	for name := range fields {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		c.Warnings = append(c.Warnings, fmt.Sprintf("%s: unknown field %q", path, name))
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("error decoding %s: %v", path, err)
+	}
+	c.Files = append(c.Files, path)
+	return nil
+}
+
+// applyEnv sets every field whose TRADER_* variable is set
+func (c *Config) applyEnv() error {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		env := t.Field(i).Tag.Get("env")
+		if len(env) == 0 {
+			continue
+		}
+		value, ok := os.LookupEnv(EnvPrefix + env)
+		if !ok {
+			continue
+		}
+
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s%s %q: %v", EnvPrefix, env, value, err)
+			}
+			field.SetInt(int64(n))
+		case reflect.Float64:
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s%s %q: %v", EnvPrefix, env, value, err)
+			}
+			field.SetFloat(f)
+		}
+	}
+	return nil
+}
+
+// Validate reports every out of range value at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	endpoint, err := url.Parse(c.RPCEndpoint)
+	check(err == nil && (endpoint.Scheme == "http" || endpoint.Scheme == "https") && len(endpoint.Host) > 0,
+		"rpcEndpoint %q is not an http(s) url", c.RPCEndpoint)
+	if len(c.WSEndpoint) > 0 {
+		endpoint, err := url.Parse(c.WSEndpoint)
+		check(err == nil && (endpoint.Scheme == "ws" || endpoint.Scheme == "wss") && len(endpoint.Host) > 0,
+			"wsEndpoint %q is not a ws(s) url", c.WSEndpoint)
+	}
+	check(len(c.Keystore) > 0, "keystore is empty")
+	if len(c.TraderPVTK) > 0 {
+		_, err := solana.PrivateKeyFromBase58(c.TraderPVTK)
+		check(err == nil, "traderPvtK is not a base58 private key")
+	}
+
+	check(c.BalanceRisk > 0 && c.BalanceRisk <= 100, "balanceRisk %v must be a percent in (0, 100]", c.BalanceRisk)
+	check(c.TradeStopLoss >= 0 && c.TradeStopLoss < 100, "tradeStopLoss %v must be a percent in [0, 100), 0 disables it", c.TradeStopLoss)
+	check(c.TotalStopLoss >= 0 && c.TotalStopLoss < 100, "totalStopLoss %v must be a percent in [0, 100), 0 disables it", c.TotalStopLoss)
+	check(c.TrailingStop >= 0 && c.TrailingStop < 100, "trailingStop %v must be a percent in [0, 100), 0 disables it", c.TrailingStop)
+	check(c.TakeProfit >= 0, "takeProfit %v must not be negative, 0 disables it", c.TakeProfit)
+	check(c.Slippage >= 0 && c.Slippage <= 1, "slippage %v must be a fraction in [0, 1], 0.04 is 4%%", c.Slippage)
+	check(c.Traders >= 1 && c.Traders <= MaxTraders, "traders %d must be between 1 and %d", c.Traders, MaxTraders)
+	check(c.MaxPositions >= 1, "maxPositions %d must be at least 1", c.MaxPositions)
+	check(c.MaxHoldTime >= 0, "maxHoldTime %d must not be negative, 0 disables it", c.MaxHoldTime)
+	check(c.PriorityFeePercentile >= 0 && c.PriorityFeePercentile <= 100, "priorityFeePercentile %v must be in [0, 100]", c.PriorityFeePercentile)
+	check(c.MaxPriorityFee >= 0, "maxPriorityFee %v must not be negative, 0 disables the cap", c.MaxPriorityFee)
+	check(c.ComputeHeadroom >= 0, "computeHeadroom %v must not be negative", c.ComputeHeadroom)
+
+	return errors.Join(errs...)
+}
+
+// Save writes the config to path, readable only by its owner
+func (c *Config) Save(path string) error {
+	configData, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling config: %v", err)
+	}
+
+	if err := os.WriteFile(path, configData, 0600); err != nil {
+		return fmt.Errorf("error writing config file: %v", err)
+	}
+	// WriteFile keeps the mode of an existing file, old configs were written 0644
+	return os.Chmod(path, 0600)
+}
+
+// RemoveField deletes a field from the config file at path, leaving the rest of the file as it was
+func RemoveField(path, name string) error {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		fmt.Println("Error unmarshalling config:", err)
-		os.Exit(1)
+		return fmt.Errorf("error reading config file: %v", err)
 	}
 
-	return &config
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("error decoding %s: %v", path, err)
+	}
+	if _, ok := fields[name]; !ok {
+		return nil
+	}
+	delete(fields, name)
+
+	data, err = json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling config: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("error writing config file: %v", err)
+	}
+	return os.Chmod(path, 0600)
+}
+
+func jsonFields() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
 }
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func write(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadCreatesDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+
+	config, err := Load(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Slippage != defaultConfig.Slippage || config.Traders != defaultConfig.Traders || config.RPCEndpoint != defaultConfig.RPCEndpoint {
+		t.Errorf("loaded %+v, want the defaults", config)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("default config not written: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("config written with mode %o, want 600", mode)
+	}
+}
+
+func TestLoadOverlayOrder(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	write(t, path, `{"slippage": 0.1, "traders": 2, "takeProfit": 30}`)
+	write(t, OverlayPath(path, "paper"), `{"slippage": 0.2, "traders": 3}`)
+	t.Setenv(EnvPrefix+"TRADERS", "4")
+
+	tests := []struct {
+		profile    string
+		slippage   float64
+		traders    int
+		takeProfit float64
+		files      int
+	}{
+		// the overlay wins over the base and the environment over both, fields nobody sets keep the base's
+		{"paper", 0.2, 4, 30, 2},
+		// a profile without an overlay is just the base
+		{"live", 0.1, 4, 30, 1},
+		{"", 0.1, 4, 30, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			config, err := Load(path, tt.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Slippage != tt.slippage || config.Traders != tt.traders || config.TakeProfit != tt.takeProfit {
+				t.Errorf("slippage %v traders %d takeProfit %v, want %v %d %v",
+					config.Slippage, config.Traders, config.TakeProfit, tt.slippage, tt.traders, tt.takeProfit)
+			}
+			if len(config.Files) != tt.files {
+				t.Errorf("merged %v, want %d files", config.Files, tt.files)
+			}
+		})
+	}
+}
+
+func TestOverlayPath(t *testing.T) {
+	if got := OverlayPath(filepath.Join("conf", "config.json"), "live"); got != filepath.Join("conf", "config.live.json") {
+		t.Errorf("OverlayPath = %s, want conf/config.live.json", got)
+	}
+}
+
+func TestEnv(t *testing.T) {
+	tests := []struct {
+		name, env, value string
+		check            func(c *Config) bool
+		wantErr          bool
+	}{
+		{"string", "RPC_ENDPOINT", "http://localhost:8899", func(c *Config) bool { return c.RPCEndpoint == "http://localhost:8899" }, false},
+		{"int", "MAX_HOLD_TIME", "60", func(c *Config) bool { return c.MaxHoldTime == 60 }, false},
+		{"float", "BALANCE_RISK", "2.5", func(c *Config) bool { return c.BalanceRisk == 2.5 }, false},
+		{"bad int", "TRADERS", "two", nil, true},
+		{"bad float", "SLIPPAGE", "4%", nil, true},
+		// parses but is out of range, Validate catches it
+		{"out of range", "SLIPPAGE", "4", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), FileName)
+			t.Setenv(EnvPrefix+tt.env, tt.value)
+
+			config, err := Load(path, "")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("%s%s=%s loaded", EnvPrefix, tt.env, tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(config) {
+				t.Errorf("%s%s=%s not applied: %+v", EnvPrefix, tt.env, tt.value, config)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		field  string // in the error, empty when valid
+	}{
+		{"defaults", func(c *Config) {}, ""},
+		{"zero stop losses disable them", func(c *Config) { c.TradeStopLoss, c.TotalStopLoss = 0, 0 }, ""},
+		{"ws endpoint", func(c *Config) { c.WSEndpoint = "ws://localhost:8900" }, ""},
+		{"rpc endpoint scheme", func(c *Config) { c.RPCEndpoint = "ws://localhost:8899" }, "rpcEndpoint"},
+		{"ws endpoint scheme", func(c *Config) { c.WSEndpoint = "http://localhost:8900" }, "wsEndpoint"},
+		{"no keystore", func(c *Config) { c.Keystore = "" }, "keystore"},
+		{"bad private key", func(c *Config) { c.TraderPVTK = "not a key" }, "traderPvtK"},
+		{"no balance risk", func(c *Config) { c.BalanceRisk = 0 }, "balanceRisk"},
+		{"balance risk over 100", func(c *Config) { c.BalanceRisk = 101 }, "balanceRisk"},
+		{"trade stop loss of 100", func(c *Config) { c.TradeStopLoss = 100 }, "tradeStopLoss"},
+		{"negative total stop loss", func(c *Config) { c.TotalStopLoss = -1 }, "totalStopLoss"},
+		{"trailing stop of 100", func(c *Config) { c.TrailingStop = 100 }, "trailingStop"},
+		{"negative take profit", func(c *Config) { c.TakeProfit = -1 }, "takeProfit"},
+		{"slippage as a percent", func(c *Config) { c.Slippage = 4 }, "slippage"},
+		{"no traders", func(c *Config) { c.Traders = 0 }, "traders"},
+		{"too many traders", func(c *Config) { c.Traders = MaxTraders + 1 }, "traders"},
+		{"no positions", func(c *Config) { c.MaxPositions = 0 }, "maxPositions"},
+		{"negative max hold time", func(c *Config) { c.MaxHoldTime = -1 }, "maxHoldTime"},
+		{"fee percentile over 100", func(c *Config) { c.PriorityFeePercentile = 101 }, "priorityFeePercentile"},
+		{"negative max fee", func(c *Config) { c.MaxPriorityFee = -1 }, "maxPriorityFee"},
+		{"negative headroom", func(c *Config) { c.ComputeHeadroom = -1 }, "computeHeadroom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := defaultConfig
+			tt.change(&config)
+
+			err := config.Validate()
+			if tt.field == "" {
+				if err != nil {
+					t.Errorf("Validate = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.field) {
+				t.Errorf("Validate = %v, want an error about %s", err, tt.field)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEverything(t *testing.T) {
+	config := defaultConfig
+	config.Slippage = 4
+	config.Traders = 0
+
+	err := config.Validate()
+	if err == nil || !strings.Contains(err.Error(), "slippage") || !strings.Contains(err.Error(), "traders") {
+		t.Errorf("Validate = %v, want both slippage and traders", err)
+	}
+}
+
+func TestUnknownFieldWarnings(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	write(t, path, `{"slippage": 0.1, "slipage": 0.2}`)
+	write(t, OverlayPath(path, "paper"), `{"tradres": 2}`)
+
+	config, err := Load(path, "paper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Warnings) != 2 ||
+		!strings.Contains(config.Warnings[0], `"slipage"`) || !strings.Contains(config.Warnings[1], `"tradres"`) {
+		t.Errorf("warnings %q, want one each for slipage and tradres", config.Warnings)
+	}
+	if config.Slippage != 0.1 {
+		t.Errorf("slippage %v, want the known field's 0.1", config.Slippage)
+	}
+}
+
+func TestLoadBadJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	write(t, path, `{"slippage": `)
+	if _, err := Load(path, ""); err == nil {
+		t.Error("loaded a truncated config")
+	}
+}
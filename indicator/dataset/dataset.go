@@ -16,6 +16,8 @@ import (
 
 type Dataset struct {
 	Captured      int
+	Path          string        // samples are appended here
+	Horizon       time.Duration // how long after capture the price change is measured
 	pf            *pumpfun.Pumpfun
 	dsLock        sync.Mutex
 	coinsCaptured *cache.Cache
@@ -28,8 +30,13 @@ func (ds *Dataset) Capture(coin *pumpfun.Coin) error {
 	}
 	ds.coinsCaptured.Set(coin.MintAddr.String(), true, cache.DefaultExpiration)
 	compiled := coin.Compile()
+	if compiled == nil {
+		// let it be captured once it has candles
+		ds.coinsCaptured.Delete(coin.MintAddr.String())
+		return errors.New("not enough candles")
+	}
 	coinPrice := coin.Price()
-	time.Sleep(3 * time.Second)
+	time.Sleep(ds.Horizon)
 	endPrice := coin.Price()
 	answer := ds.checkChangePercentage(coinPrice, endPrice, 10)
 	return ds.writeCapture(compiled, answer)
@@ -62,7 +69,7 @@ func (ds *Dataset) writeCapture(compiled []float64, answerBool bool) error {
 	text := compiledStr + "=>" + fmt.Sprintf("%d", answer) + "\r\n"
 
 	// Open the file in append mode, create it if it doesn't exist
-	file, err := os.OpenFile("dataset.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	file, err := os.OpenFile(ds.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
 	}
@@ -91,6 +98,8 @@ func (ds *Dataset) floatArrayToString(arr []float64) string {
 func New(pf *pumpfun.Pumpfun) *Dataset {
 	return &Dataset{
 		pf:            pf,
+		Path:          "dataset.txt",
+		Horizon:       3 * time.Second,
 		coinsCaptured: cache.New(3*time.Minute, 5*time.Minute),
 	}
 }
@@ -17,7 +17,11 @@ var (
 )
 
 func ShouldBuy(coin *pumpfun.Coin) bool {
-	compiledInputs := convertFloat64ToFloat32(coin.Compile())
+	compiled := coin.Compile()
+	if compiled == nil {
+		return false
+	}
+	compiledInputs := convertFloat64ToFloat32(compiled)
 
 	var inputs = make(gonnx.Tensors)
 	inputs["inputs"] = tensor.New(
@@ -0,0 +1,221 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"trader.fun/ledger"
+	"trader.fun/pumpfun"
+)
+
+const (
+	FileName      = "journal.jsonl"
+	PaperFileName = "journal-paper.jsonl"
+)
+
+type EventType string
+
+const (
+	Signal         EventType = "signal"
+	OrderSubmitted EventType = "order_submitted"
+	OrderPending   EventType = "order_pending" // not seen to land or expire in time, reconciled later
+	OrderConfirmed EventType = "order_confirmed"
+	OrderFailed    EventType = "order_failed"
+	PositionClosed EventType = "position_closed"
+)
+
+type Side string
+
+const (
+	Buy  Side = "buy"
+	Sell Side = "sell"
+)
+
+// Event is one line of the journal. Sol is what was spent on a buy or received on a sell, fees included
+type Event struct {
+	Time         time.Time        `json:"time"`
+	Type         EventType        `json:"type"`
+	Strategy     string           `json:"strategy,omitempty"`
+	Mint         solana.PublicKey `json:"mint"`
+	BondingCurve solana.PublicKey `json:"bondingCurve"`
+	Side         Side             `json:"side,omitempty"`
+	Sol          float64          `json:"sol,omitempty"`
+	Tokens       float64          `json:"tokens,omitempty"`
+	Signature    string           `json:"signature,omitempty"`
+	PnL          float64          `json:"pnl,omitempty"`
+	Reason       string           `json:"reason,omitempty"`
+	Error        string           `json:"error,omitempty"`
+}
+
+// Journal is an append only jsonl file, a nil journal records nothing
+type Journal struct {
+	file *os.File
+	lock sync.Mutex
+}
+
+func (j *Journal) Record(e Event) error {
+	if j == nil {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error marshalling journal event: %v", err)
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing journal event: %v", err)
+	}
+	return nil
+}
+
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	return j.file.Close()
+}
+
+func Open(path string) (*Journal, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening journal: %v", err)
+	}
+	return &Journal{file: file}, nil
+}
+
+func Read(path string) ([]Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening journal: %v", err)
+	}
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("error decoding journal line %d: %v", line, err)
+		}
+		events = append(events, e)
+	}
+
+	return events, scanner.Err()
+}
+
+// Trade is the realized result of one confirmed sell
+type Trade struct {
+	Time     time.Time
+	Mint     solana.PublicKey
+	Strategy string
+	Sol      float64
+	PnL      float64
+}
+
+// Replay rebuilds the positions from the confirmed orders and returns every realized sell.
+// Sells are attributed to the strategy of the last signal seen for the mint.
+// A sell of a mint with no buy in the journal, e.g. one bought before journaling started, is skipped.
+func Replay(events []Event) (*ledger.Ledger, []Trade) {
+	positions := ledger.New("")
+	strategies := make(map[solana.PublicKey]string)
+	var trades []Trade
+
+	for _, e := range events {
+		switch {
+		case e.Type == Signal && e.Strategy != "":
+			strategies[e.Mint] = e.Strategy
+		case e.Type == OrderConfirmed && e.Side == Buy:
+			positions.Buy(pumpfun.Coin{MintAddr: e.Mint, TokenBondingCurve: e.BondingCurve}, pumpfun.RawTokens(e.Tokens), e.Sol, e.Signature, e.Time)
+		case e.Type == OrderConfirmed && e.Side == Sell:
+			pnl, err := positions.Sell(e.Mint, pumpfun.RawTokens(e.Tokens), e.Sol, e.Signature, e.Time)
+			if err != nil {
+				fmt.Println("Journal: skipping sell", e.Signature, "of", e.Mint, err)
+				continue
+			}
+			trades = append(trades, Trade{
+				Time:     e.Time,
+				Mint:     e.Mint,
+				Strategy: strategies[e.Mint],
+				Sol:      e.Sol,
+				PnL:      pnl,
+			})
+		}
+	}
+
+	return positions, trades
+}
+
+type Summary struct {
+	Key    string
+	Trades int
+	Wins   int
+	Volume float64
+	PnL    float64
+}
+
+func (s Summary) WinRate() float64 {
+	if s.Trades == 0 {
+		return 0
+	}
+	return float64(s.Wins) / float64(s.Trades) * 100
+}
+
+func ByDay(t Trade) string {
+	return t.Time.UTC().Format(time.DateOnly)
+}
+
+func ByStrategy(t Trade) string {
+	if t.Strategy == "" {
+		return "unknown"
+	}
+	return t.Strategy
+}
+
+// Summarize groups trades by key, sorted by key
+func Summarize(trades []Trade, key func(Trade) string) []Summary {
+	groups := make(map[string]*Summary)
+	for _, t := range trades {
+		k := key(t)
+		s, ok := groups[k]
+		if !ok {
+			s = &Summary{Key: k}
+			groups[k] = s
+		}
+		s.Trades++
+		if t.PnL > 0 {
+			s.Wins++
+		}
+		s.Volume += t.Sol
+		s.PnL += t.PnL
+	}
+
+	summaries := make([]Summary, 0, len(groups))
+	for _, s := range groups {
+		summaries = append(summaries, *s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Key < summaries[j].Key
+	})
+	return summaries
+}
@@ -0,0 +1,244 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	SchemaVersion = 1
+	FileName      = "keystore.json"
+	// DefaultKey is the key of the first trader
+	DefaultKey = "trader"
+)
+
+// scrypt cost, about 32MB and a tenth of a second per unlock
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	keyLen  = 32
+)
+
+var (
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+	ErrKeyNotFound     = errors.New("key not found in keystore")
+	ErrKeyExists       = errors.New("key already exists in keystore")
+)
+
+// Key is a private key sealed with AES-256-GCM under a scrypt derived key, the public key is the additional data
+type Key struct {
+	PublicKey  solana.PublicKey `json:"publicKey"`
+	Salt       []byte           `json:"salt"`
+	N          int              `json:"n"`
+	R          int              `json:"r"`
+	P          int              `json:"p"`
+	Nonce      []byte           `json:"nonce"`
+	Ciphertext []byte           `json:"ciphertext"`
+}
+
+// Keystore is a file of named encrypted keys, it's only ever written with 0600 permissions
+type Keystore struct {
+	Version int             `json:"version"`
+	Keys    map[string]*Key `json:"keys"`
+
+	path string
+	lock sync.Mutex
+}
+
+// Add seals privateKey under name and saves the keystore
+func (ks *Keystore) Add(name string, privateKey solana.PrivateKey, passphrase string) error {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return errors.New("invalid private key")
+	}
+	if len(passphrase) == 0 {
+		return errors.New("empty passphrase")
+	}
+
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+
+	if _, ok := ks.Keys[name]; ok {
+		return fmt.Errorf("%w: %s", ErrKeyExists, name)
+	}
+
+	key, err := seal(privateKey, passphrase)
+	if err != nil {
+		return err
+	}
+	ks.Keys[name] = key
+
+	return ks.save()
+}
+
+// Unlock opens the key under name
+func (ks *Keystore) Unlock(name, passphrase string) (solana.PrivateKey, error) {
+	ks.lock.Lock()
+	key, ok := ks.Keys[name]
+	ks.lock.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, name)
+	}
+	return key.open(passphrase)
+}
+
+// Names lists the keys in name order
+func (ks *Keystore) Names() []string {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+
+	names := make([]string, 0, len(ks.Keys))
+	for name := range ks.Keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (ks *Keystore) save() error {
+	data, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling keystore: %v", err)
+	}
+
+	tmp := ks.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("error writing keystore: %v", err)
+	}
+	if err := os.Rename(tmp, ks.path); err != nil {
+		return fmt.Errorf("error replacing keystore: %v", err)
+	}
+	return nil
+}
+
+func seal(privateKey solana.PrivateKey, passphrase string) (*Key, error) {
+	key := &Key{
+		PublicKey: privateKey.PublicKey(),
+		Salt:      make([]byte, 32),
+		N:         scryptN,
+		R:         scryptR,
+		P:         scryptP,
+	}
+	if _, err := rand.Read(key.Salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := key.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(key.Nonce); err != nil {
+		return nil, err
+	}
+	key.Ciphertext = aead.Seal(nil, key.Nonce, privateKey, key.PublicKey.Bytes())
+
+	return key, nil
+}
+
+func (k *Key) open(passphrase string) (solana.PrivateKey, error) {
+	aead, err := k.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := aead.Open(nil, k.Nonce, k.Ciphertext, k.PublicKey.Bytes())
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	privateKey := solana.PrivateKey(plain)
+	if len(privateKey) != ed25519.PrivateKeySize || !privateKey.PublicKey().Equals(k.PublicKey) {
+		return nil, errors.New("keystore entry is corrupt")
+	}
+	return privateKey, nil
+}
+
+func (k *Key) aead(passphrase string) (cipher.AEAD, error) {
+	derived, err := scrypt.Key([]byte(passphrase), k.Salt, k.N, k.R, k.P, keyLen)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving key: %v", err)
+	}
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ReadSolanaKeypair reads a keypair file written by solana-keygen, a json array of the 64 key bytes
+func ReadSolanaKeypair(path string) (solana.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var bytes []byte
+	var ints []int
+	if err := json.Unmarshal(data, &ints); err != nil {
+		return nil, fmt.Errorf("error decoding keypair %s: %v", path, err)
+	}
+	for _, b := range ints {
+		if b < 0 || b > 255 {
+			return nil, fmt.Errorf("error decoding keypair %s: byte out of range", path)
+		}
+		bytes = append(bytes, byte(b))
+	}
+
+	if len(bytes) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("keypair %s has %d bytes, want %d", path, len(bytes), ed25519.PrivateKeySize)
+	}
+	privateKey := solana.PrivateKey(bytes)
+	// the second half is the public key, a mismatch means the file is damaged
+	if !privateKey.PublicKey().Equals(solana.PublicKeyFromBytes(bytes[32:])) {
+		return nil, fmt.Errorf("keypair %s public key doesn't match its private key", path)
+	}
+	return privateKey, nil
+}
+
+// Load reads the keystore at path, a missing file is an empty keystore
+func Load(path string) (*Keystore, error) {
+	ks := New(path)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ks, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading keystore: %v", err)
+	}
+
+	if err := json.Unmarshal(data, ks); err != nil {
+		return nil, fmt.Errorf("error decoding keystore: %v", err)
+	}
+	if ks.Version > SchemaVersion {
+		return nil, fmt.Errorf("keystore schema version %d is newer than supported version %d", ks.Version, SchemaVersion)
+	}
+	if ks.Keys == nil {
+		ks.Keys = make(map[string]*Key)
+	}
+
+	return ks, nil
+}
+
+func New(path string) *Keystore {
+	return &Keystore{
+		Version: SchemaVersion,
+		Keys:    make(map[string]*Key),
+		path:    path,
+	}
+}
@@ -0,0 +1,97 @@
+package keystore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	privateKey := solana.NewWallet().PrivateKey
+
+	ks := New(path)
+	if err := ks.Add(DefaultKey, privateKey, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("keystore written with mode %o, want 600", mode)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlocked, err := loaded.Unlock(DefaultKey, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !unlocked.PublicKey().Equals(privateKey.PublicKey()) || unlocked.String() != privateKey.String() {
+		t.Error("unlocked a different key than was added")
+	}
+
+	if _, err := loaded.Unlock(DefaultKey, "wrong horse"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Unlock with the wrong passphrase = %v, want ErrWrongPassphrase", err)
+	}
+	if _, err := loaded.Unlock("missing", "correct horse"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Unlock of a missing key = %v, want ErrKeyNotFound", err)
+	}
+	if err := loaded.Add(DefaultKey, privateKey, "correct horse"); !errors.Is(err, ErrKeyExists) {
+		t.Errorf("Add of an existing name = %v, want ErrKeyExists", err)
+	}
+}
+
+func TestTamper(t *testing.T) {
+	privateKey := solana.NewWallet().PrivateKey
+	sealed, err := seal(privateKey, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(k *Key)
+	}{
+		{"ciphertext", func(k *Key) { k.Ciphertext[0] ^= 1 }},
+		{"nonce", func(k *Key) { k.Nonce[0] ^= 1 }},
+		{"salt", func(k *Key) { k.Salt[0] ^= 1 }},
+		// the public key is the additional data, swapping it for another key's breaks the seal
+		{"public key", func(k *Key) { k.PublicKey = solana.NewWallet().PublicKey() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := *sealed
+			key.Ciphertext = append([]byte(nil), sealed.Ciphertext...)
+			key.Nonce = append([]byte(nil), sealed.Nonce...)
+			key.Salt = append([]byte(nil), sealed.Salt...)
+			tt.tamper(&key)
+
+			if _, err := key.open("correct horse"); !errors.Is(err, ErrWrongPassphrase) {
+				t.Errorf("opened a key with a tampered %s: %v", tt.name, err)
+			}
+		})
+	}
+
+	if _, err := sealed.open("correct horse"); err != nil {
+		t.Errorf("untouched key doesn't open: %v", err)
+	}
+}
+
+func TestAddRejects(t *testing.T) {
+	ks := New(filepath.Join(t.TempDir(), FileName))
+	if err := ks.Add(DefaultKey, solana.NewWallet().PrivateKey, ""); err == nil {
+		t.Error("added a key with an empty passphrase")
+	}
+	if err := ks.Add(DefaultKey, solana.PrivateKey{1, 2, 3}, "correct horse"); err == nil {
+		t.Error("added a short private key")
+	}
+}
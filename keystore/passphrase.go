@@ -0,0 +1,50 @@
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// PassphraseEnv unlocks the keystore without a prompt, for running unattended
+const PassphraseEnv = "TRADER_KEYSTORE_PASSPHRASE"
+
+// Passphrase reads the passphrase from PassphraseEnv, or prompts for it on the terminal.
+// confirm asks twice, for when a new key is sealed.
+func Passphrase(confirm bool) (string, error) {
+	if passphrase, ok := os.LookupEnv(PassphraseEnv); ok {
+		return passphrase, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("no terminal to prompt for the keystore passphrase, set %s", PassphraseEnv)
+	}
+
+	passphrase, err := prompt(fd, "Keystore passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		again, err := prompt(fd, "Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("passphrases don't match")
+		}
+	}
+	return passphrase, nil
+}
+
+func prompt(fd int, message string) (string, error) {
+	fmt.Fprint(os.Stderr, message)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("error reading passphrase: %v", err)
+	}
+	return string(passphrase), nil
+}
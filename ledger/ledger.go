@@ -0,0 +1,344 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"trader.fun/pumpfun"
+)
+
+const (
+	SchemaVersion  = 2 // 2 keeps lots in raw token units, 1 kept them in whole tokens
+	FileName       = "positions.json"
+	legacyFileName = "history.json"
+)
+
+var ErrNotHolding = errors.New("you must be holding this token to sell it")
+
+// Lot is one buy, Tokens and SolCost shrink as the lot is sold off first in first out.
+// Tokens are raw token units so selling everything held always closes the position.
+type Lot struct {
+	Tokens     uint64    `json:"tokens"`
+	SolCost    float64   `json:"solCost"`
+	EntryPrice float64   `json:"entryPrice"`
+	Signature  string    `json:"signature"`
+	Time       time.Time `json:"time"`
+}
+
+type Position struct {
+	Mint                   solana.PublicKey `json:"mint"`
+	TokenBondingCurve      solana.PublicKey `json:"tokenBondingCurve"`
+	AssociatedBondingCurve solana.PublicKey `json:"associatedBondingCurve"`
+	Lots                   []*Lot           `json:"lots"`
+	RealizedPnL            float64          `json:"realizedPnl"`
+}
+
+type Ledger struct {
+	Version     int                            `json:"version"`
+	Positions   map[solana.PublicKey]*Position `json:"positions"`
+	RealizedPnL float64                        `json:"realizedPnl"` // across closed positions too
+	path        string
+	lock        sync.Mutex
+}
+
+// Tokens is the raw token units held
+func (p *Position) Tokens() (tokens uint64) {
+	for _, lot := range p.Lots {
+		tokens += lot.Tokens
+	}
+	return
+}
+
+// Amount is the whole tokens held
+func (p *Position) Amount() float64 {
+	return pumpfun.WholeTokens(p.Tokens())
+}
+
+func (p *Position) SolCost() (cost float64) {
+	for _, lot := range p.Lots {
+		cost += lot.SolCost
+	}
+	return
+}
+
+// EntryPrice is the average sol paid per token still held
+func (p *Position) EntryPrice() float64 {
+	amount := p.Amount()
+	if amount == 0 {
+		return 0
+	}
+	return p.SolCost() / amount
+}
+
+func (p *Position) Opened() time.Time {
+	if len(p.Lots) == 0 {
+		return time.Time{}
+	}
+	return p.Lots[0].Time
+}
+
+func (p *Position) UnrealizedPnL(price float64) float64 {
+	return p.Amount()*price - p.SolCost()
+}
+
+func (p *Position) Coin() pumpfun.Coin {
+	return pumpfun.Coin{
+		MintAddr:               p.Mint,
+		TokenBondingCurve:      p.TokenBondingCurve,
+		AssociatedBondingCurve: p.AssociatedBondingCurve,
+	}
+}
+
+func (p *Position) clone() *Position {
+	c := *p
+	c.Lots = make([]*Lot, len(p.Lots))
+	for i, lot := range p.Lots {
+		l := *lot
+		c.Lots[i] = &l
+	}
+	return &c
+}
+
+// Buy adds a lot for raw tokens that cost solCost, fees included
+func (l *Ledger) Buy(coin pumpfun.Coin, tokens uint64, solCost float64, signature string, at time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	pos, ok := l.Positions[coin.MintAddr]
+	if !ok {
+		pos = &Position{Mint: coin.MintAddr}
+		l.Positions[coin.MintAddr] = pos
+	}
+	if !coin.TokenBondingCurve.IsZero() {
+		pos.TokenBondingCurve = coin.TokenBondingCurve
+	}
+	if !coin.AssociatedBondingCurve.IsZero() {
+		pos.AssociatedBondingCurve = coin.AssociatedBondingCurve
+	}
+
+	var entryPrice float64
+	if tokens > 0 {
+		entryPrice = solCost / pumpfun.WholeTokens(tokens)
+	}
+	pos.Lots = append(pos.Lots, &Lot{
+		Tokens:     tokens,
+		SolCost:    solCost,
+		EntryPrice: entryPrice,
+		Signature:  signature,
+		Time:       at,
+	})
+}
+
+// Sell closes raw tokens first in first out against solProceeds and returns the realized pnl
+func (l *Ledger) Sell(mint solana.PublicKey, tokens uint64, solProceeds float64, signature string, at time.Time) (float64, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	pos, ok := l.Positions[mint]
+	if !ok {
+		return 0, ErrNotHolding
+	}
+
+	var costBasis float64
+	remaining := min(tokens, pos.Tokens())
+	for len(pos.Lots) > 0 && remaining > 0 {
+		lot := pos.Lots[0]
+		if lot.Tokens <= remaining {
+			costBasis += lot.SolCost
+			remaining -= lot.Tokens
+			pos.Lots = pos.Lots[1:]
+			continue
+		}
+		cost := lot.SolCost * float64(remaining) / float64(lot.Tokens)
+		costBasis += cost
+		lot.SolCost -= cost
+		lot.Tokens -= remaining
+		remaining = 0
+	}
+
+	realized := solProceeds - costBasis
+	pos.RealizedPnL += realized
+	l.RealizedPnL += realized
+
+	if len(pos.Lots) == 0 {
+		delete(l.Positions, mint)
+	}
+
+	return realized, nil
+}
+
+func (l *Ledger) Position(mint solana.PublicKey) (*Position, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	pos, ok := l.Positions[mint]
+	if !ok {
+		return nil, false
+	}
+	return pos.clone(), true
+}
+
+// Open returns copies of the open positions, oldest first
+func (l *Ledger) Open() []*Position {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	positions := make([]*Position, 0, len(l.Positions))
+	for _, pos := range l.Positions {
+		positions = append(positions, pos.clone())
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].Opened().Before(positions[j].Opened())
+	})
+	return positions
+}
+
+// UnrealizedPnL marks every open position at the given prices, positions without a price are skipped
+func (l *Ledger) UnrealizedPnL(prices map[solana.PublicKey]float64) (pnl float64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for mint, pos := range l.Positions {
+		if price, ok := prices[mint]; ok {
+			pnl += pos.UnrealizedPnL(price)
+		}
+	}
+	return
+}
+
+// Save writes the ledger through a temp file so a crash never leaves a half written ledger
+func (l *Ledger) Save() error {
+	l.lock.Lock()
+	data, err := json.MarshalIndent(l, "", "  ")
+	l.lock.Unlock()
+	if err != nil {
+		return fmt.Errorf("error marshalling ledger: %v", err)
+	}
+
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("error writing ledger: %v", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("error replacing ledger: %v", err)
+	}
+
+	return nil
+}
+
+// TraderFileName is the ledger of a pool trader after the first, which keeps FileName
+func TraderFileName(owner solana.PublicKey) string {
+	return "positions-" + owner.String() + ".json"
+}
+
+// Load reads the ledger at path, migrating history.json into the default ledger the first time if it exists
+func Load(path string) (*Ledger, error) {
+	l := New(path)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if path != FileName {
+			return l, nil
+		}
+		if err := l.migrateLegacy(legacyFileName); err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading ledger: %v", err)
+	}
+
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("error decoding ledger: %v", err)
+	}
+	if l.Version > SchemaVersion {
+		return nil, fmt.Errorf("ledger schema version %d is newer than supported version %d", l.Version, SchemaVersion)
+	}
+	if l.Version < 2 {
+		if err := l.migrateWholeTokens(data); err != nil {
+			return nil, err
+		}
+	}
+	if l.Positions == nil {
+		l.Positions = make(map[solana.PublicKey]*Position)
+	}
+	l.Version = SchemaVersion
+
+	return l, nil
+}
+
+// migrateLegacy imports the old purchase history, a json object of token amounts.
+// It was a map keyed by pumpfun.Coin which encoding/json can't write, so only files
+// keyed by mint address carry over. Legacy entries have no cost basis and get a zero cost lot.
+func (l *Ledger) migrateLegacy(legacyPath string) error {
+	data, err := os.ReadFile(legacyPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading %s: %v", legacyPath, err)
+	}
+
+	var history map[string]float64
+	if err := json.Unmarshal(data, &history); err != nil {
+		return fmt.Errorf("error migrating %s: %v", legacyPath, err)
+	}
+
+	info, err := os.Stat(legacyPath)
+	if err != nil {
+		return err
+	}
+
+	for mint, amount := range history {
+		mintAddr, err := solana.PublicKeyFromBase58(mint)
+		if err != nil {
+			return fmt.Errorf("error migrating %s: bad mint %q: %v", legacyPath, mint, err)
+		}
+		l.Buy(pumpfun.Coin{MintAddr: mintAddr}, pumpfun.RawTokens(amount), 0, "", info.ModTime())
+	}
+
+	if err := l.Save(); err != nil {
+		return err
+	}
+	return os.Rename(legacyPath, legacyPath+".migrated")
+}
+
+// migrateWholeTokens converts the lots of a version 1 ledger, which were whole token floats, to raw token units
+func (l *Ledger) migrateWholeTokens(data []byte) error {
+	var v1 struct {
+		Positions map[solana.PublicKey]struct {
+			Lots []struct {
+				Amount float64 `json:"amount"`
+			} `json:"lots"`
+		} `json:"positions"`
+	}
+	if err := json.Unmarshal(data, &v1); err != nil {
+		return fmt.Errorf("error migrating ledger: %v", err)
+	}
+
+	for mint, pos := range l.Positions {
+		old := v1.Positions[mint].Lots
+		if len(old) != len(pos.Lots) {
+			return fmt.Errorf("error migrating ledger: lots of %s don't line up", mint)
+		}
+		for i, lot := range pos.Lots {
+			lot.Tokens = pumpfun.RawTokens(old[i].Amount)
+		}
+	}
+	return nil
+}
+
+func New(path string) *Ledger {
+	return &Ledger{
+		Version:   SchemaVersion,
+		Positions: make(map[solana.PublicKey]*Position),
+		path:      path,
+	}
+}
@@ -0,0 +1,226 @@
+package ledger
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"trader.fun/pumpfun"
+)
+
+var mint = solana.MustPublicKeyFromBase58("So11111111111111111111111111111111111111112")
+
+type lot struct {
+	tokens uint64
+	cost   float64
+}
+
+func TestSell(t *testing.T) {
+	tests := []struct {
+		name     string
+		buys     []lot
+		sell     uint64
+		proceeds float64
+		realized float64
+		left     []lot // nil when the position is closed
+	}{
+		{
+			name:     "everything",
+			buys:     []lot{{1_000_000, 1}},
+			sell:     1_000_000,
+			proceeds: 1.5,
+			realized: 0.5,
+		},
+		{
+			// a fill read back as whole tokens converts to the raw amount bought
+			name:     "everything read back from a float fill",
+			buys:     []lot{{pumpfun.RawTokens(pumpfun.WholeTokens(35_766_000_000_013)), 1}},
+			sell:     pumpfun.RawTokens(pumpfun.WholeTokens(35_766_000_000_013)),
+			proceeds: 1,
+			realized: 0,
+		},
+		{
+			name:     "part of a lot",
+			buys:     []lot{{1_000, 1}},
+			sell:     250,
+			proceeds: 0.5,
+			realized: 0.25,
+			left:     []lot{{750, 0.75}},
+		},
+		{
+			// the first lot goes whole at 1, half the second at 1.5
+			name:     "first in first out",
+			buys:     []lot{{100, 1}, {100, 3}},
+			sell:     150,
+			proceeds: 3,
+			realized: 0.5,
+			left:     []lot{{50, 1.5}},
+		},
+		{
+			name:     "more than held",
+			buys:     []lot{{100, 1}},
+			sell:     200,
+			proceeds: 2,
+			realized: 1,
+		},
+		{
+			name:     "all lots",
+			buys:     []lot{{100, 1}, {300, 1}},
+			sell:     400,
+			proceeds: 1,
+			realized: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(filepath.Join(t.TempDir(), FileName))
+			for _, buy := range tt.buys {
+				l.Buy(pumpfun.Coin{MintAddr: mint}, buy.tokens, buy.cost, "", time.Now())
+			}
+
+			realized, err := l.Sell(mint, tt.sell, tt.proceeds, "", time.Now())
+			if err != nil {
+				t.Fatalf("Sell: %v", err)
+			}
+			if math.Abs(realized-tt.realized) > 1e-9 {
+				t.Errorf("realized %v, want %v", realized, tt.realized)
+			}
+			if math.Abs(l.RealizedPnL-tt.realized) > 1e-9 {
+				t.Errorf("ledger realized %v, want %v", l.RealizedPnL, tt.realized)
+			}
+
+			pos, open := l.Position(mint)
+			if tt.left == nil {
+				if open {
+					t.Fatalf("position still open with %d tokens", pos.Tokens())
+				}
+				return
+			}
+			if !open {
+				t.Fatal("position closed, want it open")
+			}
+			if len(pos.Lots) != len(tt.left) {
+				t.Fatalf("%d lots left, want %d", len(pos.Lots), len(tt.left))
+			}
+			for i, want := range tt.left {
+				if pos.Lots[i].Tokens != want.tokens || math.Abs(pos.Lots[i].SolCost-want.cost) > 1e-9 {
+					t.Errorf("lot %d is %d tokens for %v sol, want %d for %v", i, pos.Lots[i].Tokens, pos.Lots[i].SolCost, want.tokens, want.cost)
+				}
+			}
+		})
+	}
+}
+
+func TestSellNotHolding(t *testing.T) {
+	l := New(filepath.Join(t.TempDir(), FileName))
+	if _, err := l.Sell(mint, 1, 1, "", time.Now()); !errors.Is(err, ErrNotHolding) {
+		t.Errorf("Sell = %v, want ErrNotHolding", err)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "positions-test.json")
+	l := New(path)
+	l.Buy(pumpfun.Coin{MintAddr: mint}, 1_234_567_891, 0.5, "sig", time.Now())
+	if err := l.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos, ok := loaded.Position(mint)
+	if !ok || pos.Tokens() != 1_234_567_891 || pos.SolCost() != 0.5 {
+		t.Errorf("loaded %+v, want 1234567891 tokens for 0.5 sol", pos)
+	}
+}
+
+func TestLoadVersion1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "positions-test.json")
+	v1 := `{"version":1,"positions":{"` + mint.String() + `":{"mint":"` + mint.String() + `","lots":[` +
+		`{"amount":1234.567891,"solCost":0.5},{"amount":0.000001,"solCost":0}]}},"realizedPnl":0}`
+	if err := os.WriteFile(path, []byte(v1), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Version != SchemaVersion {
+		t.Errorf("version %d, want %d", l.Version, SchemaVersion)
+	}
+	pos, ok := l.Position(mint)
+	if !ok || len(pos.Lots) != 2 {
+		t.Fatalf("position %+v, want 2 lots", pos)
+	}
+	if pos.Lots[0].Tokens != 1_234_567_891 || pos.Lots[1].Tokens != 1 {
+		t.Errorf("lots of %d and %d tokens, want 1234567891 and 1", pos.Lots[0].Tokens, pos.Lots[1].Tokens)
+	}
+}
+
+func TestLoadNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "positions-test.json")
+	if err := os.WriteFile(path, []byte(`{"version":99}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("loaded a ledger from a newer version")
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, legacyFileName)
+	if err := os.WriteFile(legacy, []byte(`{"`+mint.String()+`":1234.567891}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	l := New(filepath.Join(dir, FileName))
+	if err := l.migrateLegacy(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	pos, ok := l.Position(mint)
+	if !ok {
+		t.Fatal("legacy holding not migrated")
+	}
+	if pos.Tokens() != 1_234_567_891 || pos.SolCost() != 0 {
+		t.Errorf("migrated %d tokens for %v sol, want 1234567891 for 0", pos.Tokens(), pos.SolCost())
+	}
+	if _, err := os.Stat(legacy + ".migrated"); err != nil {
+		t.Errorf("legacy file not renamed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, FileName)); err != nil {
+		t.Errorf("ledger not saved: %v", err)
+	}
+
+	// a sell of the whole migrated holding closes it
+	if _, err := l.Sell(mint, pos.Tokens(), 1, "", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, open := l.Position(mint); open {
+		t.Error("migrated position still open after selling it all")
+	}
+}
+
+func TestMigrateLegacyBadMint(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, legacyFileName)
+	if err := os.WriteFile(legacy, []byte(`{"not a mint":1}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := New(filepath.Join(dir, FileName)).migrateLegacy(legacy); err == nil {
+		t.Error("migrated a bad mint")
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		t.Errorf("legacy file gone after a failed migration: %v", err)
+	}
+}
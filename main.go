@@ -2,50 +2,165 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"math/rand/v2"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/codingsandmore/pumpfun-portal/portal"
 	"github.com/fatih/color"
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
-	"github.com/patrickmn/go-cache"
 	"golang.org/x/time/rate"
+	"trader.fun/backtest"
 	"trader.fun/config"
-	"trader.fun/indicator"
 	"trader.fun/indicator/dataset"
+	"trader.fun/journal"
+	"trader.fun/keystore"
+	"trader.fun/ledger"
+	"trader.fun/papertrade"
+	"trader.fun/position"
 	"trader.fun/pumpfun"
+	"trader.fun/strategy"
+	"trader.fun/tape"
+	"trader.fun/trader"
+	"trader.fun/wallet"
 )
 
 var (
-	cfg       = config.LoadConfig()
+	cfg        *config.Config
+	configPath string
+	rpcClient  *rpc.Client
+)
+
+type command struct {
+	usage   string
+	profile string // config overlay loaded unless -profile says otherwise
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = map[string]command{
+	"capture":  {"capture [-out dataset.txt] [-samples 10000] [-horizon 3s]", "", capture_dataset},
+	"balance":  {"balance [-in dataset.txt] [-out balanced_dataset.txt] [-ratio 2]", "", balance_dataset},
+	"paper":    {"paper [-sol 1] [-strategy indicator|fibonacci] [-horizon 3s] [-min-mcap 60] [-flatten=true]", "paper", virtual_trader},
+	"live":     {"live [-horizon 3s] [-flatten]", "live", live_trader},
+	"backtest": {"backtest [-sol 1] [-strategy momentum|fibonacci] [-buys 5] [-min-mcap 60] [-sample 1m] <tape file or directory>", "paper", run_backtest},
+	"record":   {"record [directory]", "", record},
+	"journal":  {"journal [journal.jsonl|journal-paper.jsonl]", "", journal_summary},
+	"wallet":   {"wallet [-trader 0] balance | withdraw <address> <sol> | positions | simulate buy <mint> <sol> | simulate sell <mint> <percent> | simulate withdraw <address> <sol>", "live", wallet_command},
+	"keys":     {"keys list | new [name] | import <solana keypair.json> [name] | migrate", "live", keys_command},
+}
+
+// errUsage makes a command exit with status 2 after its usage is printed
+var errUsage = errors.New("usage")
+
+func main() {
+	flag.StringVar(&configPath, "config", config.FileName, "base config file")
+	profile := flag.String("profile", "", "config overlay to merge over the base, paper and live by default for their commands")
+	flag.Usage = usage
+	flag.Parse()
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if len(*profile) == 0 {
+		*profile = cmd.profile
+	}
+
+	var err error
+	if cfg, err = config.Load(configPath, *profile); err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid config:", err)
+		os.Exit(1)
+	}
+	for _, warning := range cfg.Warnings {
+		fmt.Fprintln(os.Stderr, "Warning:", warning)
+	}
+	if len(cfg.TraderPVTK) > 0 {
+		fmt.Fprintln(os.Stderr, "Warning:", configPath, "has a plaintext traderPvtK, move it into the keystore with `trader keys migrate`")
+	}
 	rpcClient = rpc.NewWithCustomRPCClient(rpc.NewWithLimiter(
 		cfg.RPCEndpoint,
 		rate.Every(time.Second*10), // time frame
 		35,                         // limit of requests per time frame
 	))
-)
+	pumpfun.SetRPCClient(rpcClient)
 
-func main() {
-	//capture_dataset()
-	//balance_dataset()
-	//virtual_trader()
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.run(ctx, flag.Args()[1:])
+	}()
+
+	select {
+	case err := <-done:
+		cancel()
+		if errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "usage: trader", cmd.usage)
+			os.Exit(2)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case sig := <-signals:
+		fmt.Fprintln(os.Stderr, "Received", sig, "shutting down, send it again to exit now")
+		cancel()
+		// let the command flatten positions and close its journal and tapes
+		select {
+		case <-done:
+		case <-signals:
+		}
+		os.Exit(128 + int(sig.(syscall.Signal)))
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: trader [-config config.json] [-profile paper|live] <command> [flags]")
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, name := range []string{"capture", "balance", "paper", "live", "backtest", "record", "journal", "wallet", "keys"} {
+		fmt.Fprintln(os.Stderr, "  "+commands[name].usage)
+	}
+}
+
+// parse parses a command's flags, -h prints them and exits with the usage status
+func parse(fs *flag.FlagSet, args []string) error {
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	return nil
 }
 
-func balance_dataset() {
-	fileData, err := ioutil.ReadFile("dataset.txt")
+func balance_dataset(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("balance", flag.ContinueOnError)
+	in := fs.String("in", "dataset.txt", "captured dataset")
+	out := fs.String("out", "balanced_dataset.txt", "balanced dataset to write")
+	ratio := fs.Float64("ratio", 2, "bad samples kept per good sample")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	fileData, err := ioutil.ReadFile(*in)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	goodDelim := []byte("=>1")
 	goodCoin := bytes.Count(fileData, goodDelim)
-	maxCollect := int(float64(goodCoin) * 3)
+	toBC := int(float64(goodCoin) * *ratio)
 
-	toBC := maxCollect - goodCoin
 	ncollected := 0
 	var collected []string
 
@@ -59,10 +174,9 @@ func balance_dataset() {
 	}
 
 	// Open or create the file
-	file, err := os.Create("balanced_dataset.txt")
+	file, err := os.Create(*out)
 	if err != nil {
-		fmt.Println("Error creating file:", err)
-		return
+		return fmt.Errorf("error creating file: %v", err)
 	}
 	defer file.Close()
 
@@ -74,91 +188,612 @@ func balance_dataset() {
 	content := strings.Join(collected, "\r\n")
 	_, err = file.WriteString(content)
 	if err != nil {
-		fmt.Println("Error writing to file:", err)
-		return
+		return fmt.Errorf("error writing to file: %v", err)
 	}
 
 	fmt.Println("found", goodCoin, "good coins")
+	return nil
 }
 
-func virtual_trader() {
-	var pf *pumpfun.Pumpfun
-	var tradeChan = make(chan *pumpfun.Coin, 1)
-	var trading = false
+func virtual_trader(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("paper", flag.ContinueOnError)
+	sol := fs.Float64("sol", 1, "starting paper sol balance of each trader")
+	name := fs.String("strategy", "indicator", "indicator or fibonacci")
+	horizon := fs.Duration("horizon", 3*time.Second, "sell the indicator's buys after this long, zero to only use the exit rules")
+	minMarketCap := fs.Float64("min-mcap", 60, "market cap in sol the fibonacci strategy only trades over")
+	flatten := fs.Bool("flatten", true, "sell open positions on shutdown, paper positions aren't kept between runs")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
 
-	ch := cache.New(1*time.Minute, 1*time.Minute)
+	var s strategy.Strategy
+	switch *name {
+	case "indicator":
+		s = indicatorStrategy(*horizon)
+	case "fibonacci":
+		s = strategy.NewFibonacci(*minMarketCap)
+	default:
+		return errUsage
+	}
 
-	discoverTrade := func(p *portal.NewTradeResponse) {
-		if len(p.BondingCurveKey) == 0 || len(p.Mint) == 0 || p.MarketCapSol == 0 || pf == nil {
-			return
-		}
+	trades, err := journal.Open(journal.PaperFileName)
+	if err != nil {
+		return err
+	}
+	defer trades.Close()
 
-		_, found := ch.Get(p.Mint)
-		if found {
-			return
-		}
-		ch.Set(p.Mint, true, cache.DefaultExpiration)
+	prices, err := priceFeed(ctx)
+	if err != nil {
+		return err
+	}
+
+	var traders []*trader.Trader
+	for i := range max(cfg.Traders, 1) {
+		t := trader.New(fmt.Sprintf("paper-%d", i), &trader.Paper{
+			Wallet:   papertrade.New(*sol),
+			Client:   rpcClient,
+			Journal:  trades,
+			Slippage: cfg.Slippage,
+			Prices:   prices,
+		})
+		t.BalanceRisk = cfg.BalanceRisk
+		t.MaxPositions = cfg.MaxPositions
+		traders = append(traders, t)
+	}
 
-		coin := &pumpfun.Coin{
-			MintAddr:          solana.MPK(p.Mint),
-			TokenBondingCurve: solana.MPK(p.BondingCurveKey),
-			MarketCap:         p.MarketCapSol,
+	pool := trader.NewPool(s, traders, trades)
+	pool.Prices = prices
+	return run_pool(ctx, pool, *flatten)
+}
+
+func live_trader(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("live", flag.ContinueOnError)
+	horizon := fs.Duration("horizon", 3*time.Second, "sell after this long, zero to only use the exit rules")
+	flatten := fs.Bool("flatten", false, "sell open positions on shutdown instead of keeping them in the ledger")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	wallets, err := traderWallets()
+	if err != nil {
+		return err
+	}
+
+	trades, err := journal.Open(journal.FileName)
+	if err != nil {
+		return err
+	}
+	defer trades.Close()
+
+	// keep a blockhash ready so orders don't wait on the rpc for one
+	go wallets[0].Blockhashes.Run(ctx)
+
+	prices, err := priceFeed(ctx)
+	if err != nil {
+		return err
+	}
+
+	var traders []*trader.Trader
+	for _, sw := range wallets {
+		sw.Journal = trades
+
+		manager := position.NewManager(sw, cfg)
+		manager.Prices = prices
+		go manager.Run(ctx)
+
+		t := trader.New(sw.Wallet.PublicKey().String(), &trader.Live{
+			Wallet:   sw,
+			Manager:  manager,
+			Slippage: cfg.Slippage,
+			Prices:   prices,
+		})
+		t.BalanceRisk = cfg.BalanceRisk
+		t.MaxPositions = cfg.MaxPositions
+		traders = append(traders, t)
+	}
+
+	pool := trader.NewPool(indicatorStrategy(*horizon), traders, trades)
+	pool.Prices = prices
+	return run_pool(ctx, pool, *flatten)
+}
+
+// priceFeed follows bonding curves over the rpc websocket until ctx is cancelled, Coin.Price reads from it too
+func priceFeed(ctx context.Context) (*pumpfun.PriceFeed, error) {
+	endpoint := cfg.WSEndpoint
+	if len(endpoint) == 0 {
+		var err error
+		if endpoint, err = pumpfun.WebsocketEndpoint(cfg.RPCEndpoint); err != nil {
+			return nil, err
 		}
+	}
 
-		if indicator.ShouldBuy(coin) && !trading {
-			tradeChan <- coin
+	prices := pumpfun.NewPriceFeed(endpoint, rpcClient)
+	dial, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := prices.Connect(dial); err != nil {
+		if len(cfg.WSEndpoint) == 0 {
+			return nil, fmt.Errorf("%v, set wsEndpoint if the rpc serves its websocket on another port or path", err)
 		}
+		return nil, err
+	}
+	go prices.Run(ctx)
+	pumpfun.SetPriceFeed(prices)
+	return prices, nil
+}
+
+// run_pool feeds pumpportal to the pool and prints its report every minute until ctx is cancelled
+func run_pool(ctx context.Context, pool *trader.Pool, flatten bool) error {
+	pool.Flatten = flatten
+	printReport(pool.Report())
+
+	// the market sees every trade first so prices read while handling it are already current
+	market := pumpfun.NewMarket(rpcClient)
+	go market.Run(ctx)
+	pumpfun.SetMarket(market)
+
+	onTrade := func(p *portal.NewTradeResponse) {
+		market.OnTrade(p)
+		pool.OnTrade(p)
 	}
+	pf := pumpfun.NewPumpFun(rpcClient, pool.OnNewPair, onTrade)
+	pf.Start(ctx)
+	defer pf.Close()
+
+	go func() {
+		ticker := time.NewTicker(1 * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				printReport(pool.Report())
+			}
+		}
+	}()
+
+	pool.Run(ctx)
+	printReport(pool.Report())
+	return ctx.Err()
+}
 
+func printReport(report trader.PoolReport) {
 	red := color.New(color.FgRed).SprintFunc()
 	green := color.New(color.FgGreen).SprintFunc()
-	blue := color.New(color.FgBlue).SprintFunc()
-	pf = pumpfun.NewPumpFun(rpcClient, discoverTrade)
-	var solBalance = 1.
 
-	fmt.Println(blue(fmt.Sprintf("STARTING SOL BALANCE: %.2f", solBalance)))
-	for {
-		coin := <-tradeChan
-		trading = true
-		coinPrice := coin.Price()
-		fmt.Println(blue(fmt.Sprintf("Now trading coin %s with start price %.2f and mc %.2f", coin.MintAddr.String(), coinPrice, coin.MarketCap)))
-		time.Sleep(3 * time.Second)
-		endPrice := coin.Price()
-		pc := percentageChange(coinPrice, endPrice)
-		solBalance = solBalance * (1 + (pc / 100))
-		if pc > 0 {
-			fmt.Println(green(fmt.Sprintf("PROFITTED! Coin %s was profitable by %.2f %.2f", coin.MintAddr.String(), pc, solBalance)))
+	for _, r := range append(report.Traders, report.Total) {
+		line := fmt.Sprintf("%-44s sol %10.4f  positions %3d  equity %10.4f  pnl %+10.4f", r.Name, r.Sol, len(r.Positions), r.Equity, r.PnL)
+		if r.PnL < 0 {
+			fmt.Println(red(line))
 		} else {
-			fmt.Println(red(fmt.Sprintf("YOU LOSS! Coin %s was unprofitable by %.2f %.2f", coin.MintAddr.String(), pc, solBalance)))
+			fmt.Println(green(line))
+		}
+	}
+}
+
+// traderWallets unlocks a wallet for each of cfg.Traders. The first is the keystore's trader key with the default ledger,
+// the rest are trader-1, trader-2... or derived from the trader key, and keep a ledger per public key.
+func traderWallets() ([]*wallet.SolWallet, error) {
+	ks, err := keystore.Load(cfg.Keystore)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := ks.Keys[keystore.DefaultKey]; !ok {
+		return nil, fmt.Errorf("no %s key in %s, create one with `trader keys new` or `trader keys import`", keystore.DefaultKey, cfg.Keystore)
+	}
+
+	passphrase, err := keystore.Passphrase(false)
+	if err != nil {
+		return nil, err
+	}
+
+	primary, err := wallet.New(rpcClient, ks, keystore.DefaultKey, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	wallets := []*wallet.SolWallet{primary}
+
+	for i := 1; i < cfg.Traders; i++ {
+		key, err := ks.Unlock(fmt.Sprintf("%s-%d", keystore.DefaultKey, i), passphrase)
+		if errors.Is(err, keystore.ErrKeyNotFound) {
+			key, err = wallet.Derive(primary.Wallet.PrivateKey, i)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		sw, err := wallet.NewWithLedger(rpcClient, key, ledger.TraderFileName(key.PublicKey()))
+		if err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, sw)
+	}
+
+	// one blockhash cache serves every wallet
+	blockhashes := wallet.NewBlockhashCache(rpcClient)
+	for _, sw := range wallets {
+		sw.Blockhashes = blockhashes
+		sw.Fees.Percentile = cfg.PriorityFeePercentile
+		sw.Fees.MaxFee = cfg.MaxPriorityFee
+		sw.Fees.Headroom = cfg.ComputeHeadroom
+	}
+	return wallets, nil
+}
+
+func keys_command(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	ks, err := keystore.Load(cfg.Keystore)
+	if err != nil {
+		return err
+	}
+
+	// name is the optional positional argument at i
+	name := func(i int) string {
+		if len(args) > i {
+			return args[i]
 		}
+		return keystore.DefaultKey
 	}
+
+	switch args[0] {
+	case "list":
+		for _, name := range ks.Names() {
+			fmt.Printf("%-12s %s\n", name, ks.Keys[name].PublicKey.String())
+		}
+		return nil
+	case "new":
+		passphrase, err := keystore.Passphrase(true)
+		if err != nil {
+			return err
+		}
+		key := solana.NewWallet().PrivateKey
+		if err := ks.Add(name(1), key, passphrase); err != nil {
+			return err
+		}
+		fmt.Printf("Created %s %s\n", name(1), key.PublicKey().String())
+	case "import":
+		if len(args) < 2 {
+			return errUsage
+		}
+		key, err := keystore.ReadSolanaKeypair(args[1])
+		if err != nil {
+			return err
+		}
+		passphrase, err := keystore.Passphrase(true)
+		if err != nil {
+			return err
+		}
+		if err := ks.Add(name(2), key, passphrase); err != nil {
+			return err
+		}
+		fmt.Printf("Imported %s %s\n", name(2), key.PublicKey().String())
+	case "migrate":
+		if len(cfg.TraderPVTK) == 0 {
+			fmt.Println("No plaintext key in", strings.Join(cfg.Files, ", "))
+			return nil
+		}
+		key, err := solana.PrivateKeyFromBase58(cfg.TraderPVTK)
+		if err != nil {
+			return fmt.Errorf("invalid traderPvtK: %v", err)
+		}
+		passphrase, err := keystore.Passphrase(true)
+		if err != nil {
+			return err
+		}
+		if err := ks.Add(keystore.DefaultKey, key, passphrase); err != nil {
+			return err
+		}
+
+		for _, file := range cfg.Files {
+			if err := config.RemoveField(file, "traderPvtK"); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Moved %s into %s and removed it from %s\n", key.PublicKey().String(), cfg.Keystore, strings.Join(cfg.Files, ", "))
+		if _, ok := os.LookupEnv(config.EnvPrefix + "PVTK"); ok {
+			fmt.Println("Unset", config.EnvPrefix+"PVTK as well")
+		}
+	default:
+		return errUsage
+	}
+	return nil
 }
 
-func capture_dataset() {
+// indicatorStrategy buys on the model and sells on the configured exit rules or after horizon
+func indicatorStrategy(horizon time.Duration) strategy.Strategy {
+	return strategy.Combine("indicator",
+		strategy.NewIndicator(horizon),
+		strategy.NewExits(position.RulesFromConfig(cfg)),
+	)
+}
+
+func capture_dataset(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("capture", flag.ContinueOnError)
+	out := fs.String("out", "dataset.txt", "dataset to append samples to")
+	samples := fs.Int("samples", 10_000, "stop after capturing this many samples")
+	horizon := fs.Duration("horizon", 3*time.Second, "how long after capture the price change is measured")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	// the price at the end of the horizon comes from the feed instead of a second rpc call
+	if _, err := priceFeed(ctx); err != nil {
+		return err
+	}
+
+	// candles come from the trades seen since the capture started, the api's only for coins it hasn't seen trade
+	market := pumpfun.NewMarket(rpcClient)
+	go market.Run(ctx)
+	pumpfun.SetMarket(market)
+
 	var ds *dataset.Dataset
 	discoverTrade := func(p *portal.NewTradeResponse) {
+		market.OnTrade(p)
 		if ds == nil || !strings.HasSuffix(p.Mint, "pump") {
 			return
 		}
-		if len(p.BondingCurveKey) == 0 || len(p.Mint) == 0 {
+		mint, err := solana.PublicKeyFromBase58(p.Mint)
+		if err != nil {
 			return
 		}
+		coin, err := pumpfun.NewCoin(mint)
+		if err != nil {
+			return
+		}
+		coin.MarketCap = p.MarketCapSol
 		go func() {
-			if err := ds.Capture(&pumpfun.Coin{
-				MintAddr:          solana.MPK(p.Mint),
-				TokenBondingCurve: solana.MPK(p.BondingCurveKey),
-				MarketCap:         p.MarketCapSol,
-			}); err == nil {
+			if err := ds.Capture(coin); err == nil {
 				fmt.Println("Captured data:", ds.Captured)
 			}
 		}()
 	}
-	pf := pumpfun.NewPumpFun(rpcClient, discoverTrade)
+	pf := pumpfun.NewPumpFun(rpcClient, nil, discoverTrade)
 	ds = dataset.New(pf)
+	ds.Path = *out
+	ds.Horizon = *horizon
+	pf.Start(ctx)
+	defer pf.Close()
+
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	for ds.Captured < *samples {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+func journal_summary(ctx context.Context, args []string) error {
+	path := journal.FileName
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	events, err := journal.Read(path)
+	if err != nil {
+		return err
+	}
+
+	positions, trades := journal.Replay(events)
+
+	printSummaries := func(title string, summaries []journal.Summary) {
+		fmt.Println(title)
+		for _, s := range summaries {
+			fmt.Printf("  %-12s trades %4d  win rate %6.2f%%  volume %10.4f sol  pnl %+10.4f sol\n", s.Key, s.Trades, s.WinRate(), s.Volume, s.PnL)
+		}
+	}
+	printSummaries("PER DAY", journal.Summarize(trades, journal.ByDay))
+	printSummaries("PER STRATEGY", journal.Summarize(trades, journal.ByStrategy))
+
+	fmt.Printf("REALIZED PNL %+.4f sol\n", positions.RealizedPnL)
+	for _, pos := range positions.Open() {
+		fmt.Printf("  open %s %.2f tokens at %.10f sol\n", pos.Mint.String(), pos.Amount(), pos.EntryPrice())
+	}
+	return nil
+}
+
+func run_backtest(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("backtest", flag.ContinueOnError)
+	sol := fs.Float64("sol", 1, "starting sol balance")
+	name := fs.String("strategy", "momentum", "momentum or fibonacci")
+	buys := fs.Int("buys", 5, "buys in a row before the momentum strategy enters")
+	minMarketCap := fs.Float64("min-mcap", 60, "market cap in sol the fibonacci strategy only trades over")
+	sample := fs.Duration("sample", time.Minute, "equity curve resolution")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
 
-	for ds.Captured < 10_000 {
-		time.Sleep(1 * time.Second)
+	var s strategy.Strategy
+	switch *name {
+	case "momentum":
+		s = strategy.Combine("momentum",
+			strategy.NewMomentum(*buys, 0, 0),
+			strategy.NewExits(position.RulesFromConfig(cfg)),
+		)
+	case "fibonacci":
+		// it places its own stops and targets, the configured exit rules would cut them short
+		s = strategy.NewFibonacci(*minMarketCap)
+	default:
+		return errUsage
+	}
+
+	result, err := backtest.Run(fs.Arg(0), s, backtest.Config{
+		StartingSol:  *sol,
+		BalanceRisk:  cfg.BalanceRisk,
+		Slippage:     cfg.Slippage,
+		MaxPositions: cfg.MaxPositions,
+		Sample:       *sample,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("SOL %.4f -> %.4f (%+.2f%%)\n", result.Start, result.End, percentageChange(result.Start, result.End))
+	fmt.Printf("trades %d  win rate %.2f%%  avg hold %s\n", len(result.Trades), result.WinRate, result.AvgHold.Round(time.Second))
+	fmt.Printf("max drawdown %.2f%%  sharpe %.2f\n", result.MaxDrawdown, result.Sharpe)
+	return nil
+}
+
+func record(ctx context.Context, args []string) error {
+	dir := "tapes"
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	writer := tape.NewWriter(dir)
+	defer writer.Close()
+
+	recorder := tape.NewRecorder(writer, rpcClient)
+	pf := pumpfun.NewPumpFun(rpcClient, recorder.OnPair, recorder.OnTrade)
+	pf.Start(ctx)
+	defer pf.Close()
+	go recorder.RunSnapshots(ctx)
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("Recorded events:", recorder.Recorded.Load())
+			return ctx.Err()
+		case <-ticker.C:
+			fmt.Println("Recorded events:", recorder.Recorded.Load())
+		}
+	}
+}
+
+func wallet_command(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("wallet", flag.ContinueOnError)
+	index := fs.Int("trader", 0, "trader wallet to withdraw from or simulate with")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	args = fs.Args()
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	wallets, err := traderWallets()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "balance":
+		var total float64
+		for i, sw := range wallets {
+			balance, err := sw.SolBalance()
+			if err != nil {
+				return err
+			}
+			total += balance
+			fmt.Printf("%2d %s %.9f sol\n", i, sw.Wallet.PublicKey().String(), balance)
+		}
+		fmt.Printf("TOTAL %.9f sol\n", total)
+	case "withdraw":
+		if len(args) != 3 {
+			return errUsage
+		}
+		if *index < 0 || *index >= len(wallets) {
+			return fmt.Errorf("no trader %d, there are %d", *index, len(wallets))
+		}
+		if _, err := solana.PublicKeyFromBase58(args[1]); err != nil {
+			return fmt.Errorf("invalid address %s: %v", args[1], err)
+		}
+		solAmount, err := strconv.ParseFloat(args[2], 64)
+		if err != nil || solAmount <= 0 {
+			return fmt.Errorf("invalid sol amount %s", args[2])
+		}
+		if err := wallets[*index].Withdrawl(args[1], solAmount); err != nil {
+			return err
+		}
+		fmt.Printf("Withdrew %.9f sol to %s\n", solAmount, args[1])
+	case "positions":
+		var realized, unrealized float64
+		for i, sw := range wallets {
+			fmt.Printf("%2d %s\n", i, sw.Wallet.PublicKey().String())
+			prices := make(map[solana.PublicKey]float64)
+			for _, pos := range sw.Ledger.Open() {
+				curve, err := pumpfun.GetBondingCurveInfos(ctx, rpcClient, pos.TokenBondingCurve)
+				if err != nil {
+					fmt.Printf("  %s %.2f tokens at %.10f sol, price unavailable: %v\n", pos.Mint.String(), pos.Amount(), pos.EntryPrice(), err)
+					continue
+				}
+				price := curve.SpotPrice()
+				prices[pos.Mint] = price
+				fmt.Printf("  %s %.2f tokens at %.10f sol, now %.10f, pnl %+.4f sol\n", pos.Mint.String(), pos.Amount(), pos.EntryPrice(), price, pos.UnrealizedPnL(price))
+			}
+			realized += sw.Ledger.RealizedPnL
+			unrealized += sw.Ledger.UnrealizedPnL(prices)
+		}
+		fmt.Printf("REALIZED PNL %+.4f sol  UNREALIZED PNL %+.4f sol\n", realized, unrealized)
+	case "simulate":
+		if *index < 0 || *index >= len(wallets) {
+			return fmt.Errorf("no trader %d, there are %d", *index, len(wallets))
+		}
+		return simulate(wallets[*index], args[1:])
+	default:
+		return errUsage
+	}
+	return nil
+}
+
+// simulate signs the transaction the wallet would send and prints what it would do, nothing is sent
+func simulate(sw *wallet.SolWallet, args []string) error {
+	if len(args) != 3 {
+		return errUsage
+	}
+	amount, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || amount <= 0 {
+		return fmt.Errorf("invalid amount %s", args[2])
+	}
+
+	var simulation *wallet.Simulation
+	switch args[0] {
+	case "buy", "sell":
+		mint, err := solana.PublicKeyFromBase58(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid mint %s: %v", args[1], err)
+		}
+		coin := pumpfun.Coin{MintAddr: mint}
+		if position, ok := sw.Ledger.Position(mint); ok {
+			coin = position.Coin()
+		}
+
+		if args[0] == "buy" {
+			simulation, err = sw.SimulateBuy(&coin, amount, cfg.Slippage)
+		} else {
+			simulation, err = sw.SimulateSell(&coin, amount, cfg.Slippage)
+		}
+		if err != nil {
+			return err
+		}
+	case "withdraw":
+		if simulation, err = sw.SimulateWithdrawl(args[1], amount); err != nil {
+			return err
+		}
+	default:
+		return errUsage
+	}
+
+	for _, line := range simulation.Logs {
+		fmt.Println("  " + line)
+	}
+	if simulation.Err != nil {
+		color.Red(simulation.String())
+	} else {
+		color.Green(simulation.String())
 	}
+	return nil
 }
 
 func percentageChange(oldValue, newValue float64) float64 {
@@ -0,0 +1,156 @@
+package papertrade
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"trader.fun/pumpfun"
+)
+
+var (
+	ErrInsufficientFunds = errors.New("not enough sol in paper wallet to complete this trade")
+	ErrSlippageExceeded  = errors.New("fill exceeds slippage tolerance")
+	ErrNotHolding        = errors.New("you must be holding this token to sell it")
+	ErrTooSmall          = errors.New("buy is too small to get any tokens")
+	ErrCurveComplete     = errors.New("bonding curve is complete")
+)
+
+type Position struct {
+	Coin    pumpfun.Coin
+	Tokens  uint64 // raw token units (6 decimals)
+	SolCost uint64 // lamports spent including fees
+	Opened  time.Time
+}
+
+type Fill struct {
+	Tokens   uint64 // raw token units bought or sold
+	Lamports uint64 // lamports paid (buy) or received (sell), fees included
+}
+
+// Wallet is a simulated SolWallet that fills orders against a bonding curve snapshot
+type Wallet struct {
+	Lamports  uint64
+	Positions map[solana.PublicKey]*Position
+	lock      sync.Mutex
+}
+
+// Buy mirrors SolWallet.BuyToken: it asks the curve for the tokens solAmount buys
+// and fails if their cost plus fee exceeds solAmount*(1+slippage).
+func (w *Wallet) Buy(coin *pumpfun.Coin, curve *pumpfun.BondingCurve, solAmount, slippage float64) (*Fill, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if curve.Complete {
+		return nil, ErrCurveComplete
+	}
+
+	lamports := uint64(solAmount * float64(solana.LAMPORTS_PER_SOL))
+	tokens := curve.QuoteBuy(lamports)
+	if tokens == 0 {
+		return nil, ErrTooSmall
+	}
+	cost, err := curve.BuyCost(tokens)
+	if err != nil {
+		return nil, err
+	}
+	maxCost := uint64(solAmount * (1 + slippage) * float64(solana.LAMPORTS_PER_SOL))
+	if cost > maxCost {
+		return nil, ErrSlippageExceeded
+	}
+	if cost > w.Lamports {
+		return nil, ErrInsufficientFunds
+	}
+
+	w.Lamports -= cost
+	if pos, ok := w.Positions[coin.MintAddr]; ok {
+		pos.Tokens += tokens
+		pos.SolCost += cost
+	} else {
+		w.Positions[coin.MintAddr] = &Position{
+			Coin:    *coin,
+			Tokens:  tokens,
+			SolCost: cost,
+			Opened:  time.Now(),
+		}
+	}
+
+	return &Fill{Tokens: tokens, Lamports: cost}, nil
+}
+
+// Sell mirrors SolWallet.SellToken, the fill is the curve output less the protocol fee.
+// Against a single snapshot the quote is the fill, so slippage can't be exceeded.
+func (w *Wallet) Sell(coin *pumpfun.Coin, curve *pumpfun.BondingCurve, percentage, slippage float64) (*Fill, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if percentage > 100 || percentage < 0 {
+		return nil, errors.New("sell percentage must be between 0-100")
+	}
+
+	if curve.Complete {
+		return nil, ErrCurveComplete
+	}
+
+	pos, ok := w.Positions[coin.MintAddr]
+	if !ok {
+		return nil, ErrNotHolding
+	}
+
+	tokens := uint64(float64(pos.Tokens) * (percentage / 100))
+	output := curve.QuoteSell(tokens)
+
+	w.Lamports += output
+	if tokens >= pos.Tokens {
+		delete(w.Positions, coin.MintAddr)
+	} else {
+		pos.SolCost -= uint64(float64(pos.SolCost) * (float64(tokens) / float64(pos.Tokens)))
+		pos.Tokens -= tokens
+	}
+
+	return &Fill{Tokens: tokens, Lamports: output}, nil
+}
+
+// Open returns copies of the open positions that are safe to use without the wallet lock
+func (w *Wallet) Open() []Position {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	positions := make([]Position, 0, len(w.Positions))
+	for _, pos := range w.Positions {
+		positions = append(positions, *pos)
+	}
+	return positions
+}
+
+func (w *Wallet) SolBalance() float64 {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return float64(w.Lamports) / float64(solana.LAMPORTS_PER_SOL)
+}
+
+// Equity is the sol balance plus what every position would sell for on its curve
+func (w *Wallet) Equity(curves map[solana.PublicKey]*pumpfun.BondingCurve) float64 {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	lamports := w.Lamports
+	for mint, pos := range w.Positions {
+		curve, ok := curves[mint]
+		if !ok {
+			continue
+		}
+		lamports += curve.QuoteSell(pos.Tokens)
+	}
+
+	return float64(lamports) / float64(solana.LAMPORTS_PER_SOL)
+}
+
+func New(solBalance float64) *Wallet {
+	return &Wallet{
+		Lamports:  uint64(solBalance * float64(solana.LAMPORTS_PER_SOL)),
+		Positions: make(map[solana.PublicKey]*Position),
+	}
+}
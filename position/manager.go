@@ -0,0 +1,119 @@
+package position
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"trader.fun/config"
+	"trader.fun/pumpfun"
+	"trader.fun/wallet"
+)
+
+// Manager polls the value of every position in the wallet's ledger and sells everything on the total stop loss.
+// Single positions exit through strategy.Exits, which checks Rules.
+type Manager struct {
+	Wallet        *wallet.SolWallet
+	Slippage      float64
+	TotalStopLoss float64 // percent of starting equity
+	Interval      time.Duration
+	// Prices, when set, values positions from memory instead of reading their curves over the rpc
+	Prices *pumpfun.PriceFeed
+
+	startEquity float64
+	halted      bool
+	lock        sync.Mutex
+}
+
+// Halted reports whether the total stop loss was hit, no new trades should be opened after that
+func (m *Manager) Halted() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.halted
+}
+
+// Run polls every Interval until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := m.poll(ctx); err != nil {
+			fmt.Println("Position manager:", err)
+		}
+	}
+}
+
+func (m *Manager) poll(ctx context.Context) error {
+	m.Wallet.Reconcile()
+
+	// once halted every poll retries selling whatever is still open
+	if m.Halted() {
+		if len(m.Wallet.Ledger.Open()) == 0 {
+			return nil
+		}
+		return m.Wallet.SellAll(m.Slippage)
+	}
+
+	solBalance, err := m.Wallet.SolBalance()
+	if err != nil {
+		return err
+	}
+
+	equity := solBalance
+	for _, pos := range m.Wallet.Ledger.Open() {
+		curve, err := m.curve(ctx, pos.TokenBondingCurve)
+		if err != nil {
+			// equity without this position would look like a loss, neither the baseline nor the stop loss can use it
+			return fmt.Errorf("skipping the stop loss check, could not price %s: %v", pos.Mint, err)
+		}
+
+		equity += float64(curve.QuoteSell(pos.Tokens())) / float64(solana.LAMPORTS_PER_SOL)
+	}
+
+	m.lock.Lock()
+	if m.startEquity == 0 {
+		m.startEquity = equity
+	}
+	halt := !m.halted && m.TotalStopLoss > 0 && equity <= m.startEquity*(1-m.TotalStopLoss/100)
+	if halt {
+		m.halted = true
+	}
+	m.lock.Unlock()
+
+	if halt {
+		fmt.Printf("Position manager: equity %.4f fell %.2f%% below start %.4f, halting trading\n", equity, m.TotalStopLoss, m.startEquity)
+		return m.Wallet.SellAll(m.Slippage)
+	}
+
+	return nil
+}
+
+// curve is the price feed's latest state of the curve, or the rpc's until the feed has one
+func (m *Manager) curve(ctx context.Context, bondingCurve solana.PublicKey) (*pumpfun.BondingCurve, error) {
+	if m.Prices != nil {
+		if update, ok := m.Prices.Latest(bondingCurve); ok {
+			return update.Curve, nil
+		}
+		m.Prices.Watch(bondingCurve)
+	}
+	return pumpfun.GetBondingCurveInfos(ctx, m.Wallet.RpcClient, bondingCurve)
+}
+
+func NewManager(sw *wallet.SolWallet, cfg *config.Config) *Manager {
+	return &Manager{
+		Wallet:        sw,
+		Slippage:      cfg.Slippage,
+		TotalStopLoss: cfg.TotalStopLoss,
+		Interval:      2 * time.Second,
+	}
+}
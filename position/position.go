@@ -0,0 +1,56 @@
+package position
+
+import (
+	"time"
+
+	"trader.fun/config"
+)
+
+type Exit string
+
+const (
+	Hold         Exit = ""
+	TakeProfit   Exit = "take profit"
+	StopLoss     Exit = "stop loss"
+	TrailingStop Exit = "trailing stop"
+	MaxHoldTime  Exit = "max hold time"
+)
+
+// Rules are the per-trade exits, percentages are 20.0 = 20% and zero disables a rule
+type Rules struct {
+	TakeProfit   float64
+	StopLoss     float64
+	TrailingStop float64
+	MaxHold      time.Duration
+}
+
+// Check decides whether a position bought at entry, whose best price so far was peak, should be closed at price
+func (r Rules) Check(entry, peak, price float64, opened, now time.Time) Exit {
+	if entry <= 0 || price <= 0 {
+		return Hold
+	}
+
+	change := (price - entry) / entry * 100
+
+	switch {
+	case r.StopLoss > 0 && change <= -r.StopLoss:
+		return StopLoss
+	case r.TakeProfit > 0 && change >= r.TakeProfit:
+		return TakeProfit
+	case r.TrailingStop > 0 && peak > entry && (peak-price)/peak*100 >= r.TrailingStop:
+		return TrailingStop
+	case r.MaxHold > 0 && now.Sub(opened) >= r.MaxHold:
+		return MaxHoldTime
+	}
+
+	return Hold
+}
+
+func RulesFromConfig(cfg *config.Config) Rules {
+	return Rules{
+		TakeProfit:   cfg.TakeProfit,
+		StopLoss:     cfg.TradeStopLoss,
+		TrailingStop: cfg.TrailingStop,
+		MaxHold:      time.Duration(cfg.MaxHoldTime) * time.Second,
+	}
+}
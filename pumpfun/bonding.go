@@ -7,9 +7,11 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+	"trader.fun/pumpfun/program"
 )
 
 // Pump Curve Constants
@@ -18,6 +20,15 @@ const (
 	LamportsPerSol          = 1_000_000_000
 	VirtualTokenReservesPos = 0x08
 	VirtualSolReservesPos   = 0x10
+	FeeBasisPoints          = 100 // 1% protocol fee on buys and sells
+)
+
+// every coin launches on the same curve, in raw token units and lamports
+const (
+	InitialVirtualTokenReserves = 1_073_000_000_000_000
+	InitialVirtualSolReserves   = 30_000_000_000
+	InitialRealTokenReserves    = 793_100_000_000_000
+	InitialTokenTotalSupply     = 1_000_000_000_000_000
 )
 
 type BondingCurve struct {
@@ -29,20 +40,19 @@ type BondingCurve struct {
 	Complete             bool
 }
 
-func GetBondingCurveInfos(client *rpc.Client, bondingCurve solana.PublicKey) (*BondingCurve, error) {
-	mint, err := client.GetAccountInfo(context.Background(), bondingCurve)
+func GetBondingCurveInfos(ctx context.Context, client *rpc.Client, bondingCurve solana.PublicKey) (*BondingCurve, error) {
+	mint, err := client.GetAccountInfo(ctx, bondingCurve)
 
 	if err != nil {
 		return nil, err
 	}
-
-	num := uint64(6966180631402821399)
-	buf := new(bytes.Buffer)
-	binary.Write(buf, binary.LittleEndian, num)
+	if mint.Value == nil {
+		return nil, errors.New("bonding curve account not found")
+	}
 
 	DataBytes := mint.Value.Data.GetBinary()
 
-	if !bytes.Equal(DataBytes[:8], buf.Bytes()) {
+	if len(DataBytes) < 8 || !bytes.Equal(DataBytes[:8], program.BondingCurveAccountDiscriminator[:]) {
 		return nil, errors.New("unexpected discriminator")
 	}
 
@@ -55,6 +65,9 @@ func GetBondingCurveInfos(client *rpc.Client, bondingCurve solana.PublicKey) (*B
 }
 
 func DecodeBondingData(bondingCurveData []byte) (*BondingCurve, error) {
+	if len(bondingCurveData) < 8 {
+		return nil, errors.New("bonding curve data too short")
+	}
 	data := bondingCurveData[8:]
 	bc := BondingCurve{}
 
@@ -95,21 +108,156 @@ func DecodeBondingData(bondingCurveData []byte) (*BondingCurve, error) {
 	return &bc, nil
 }
 
-func PriceInSolFromBondingCurveAddress(client *rpc.Client, bondingCurveAddress string) (float64, error) {
-	mint, err := client.GetAccountInfoWithOpts(context.Background(), solana.MPK(bondingCurveAddress), &rpc.GetAccountInfoOpts{Commitment: rpc.CommitmentConfirmed})
+// NewBondingCurveFromReserves rebuilds the curve from the virtual reserves pumpportal
+// sends with every trade, given in whole tokens and sol
+func NewBondingCurveFromReserves(vTokens, vSol float64) *BondingCurve {
+	bc := &BondingCurve{
+		VirtualTokenReserves: uint64(math.Round(vTokens * math.Pow10(TokenDecimals))),
+		VirtualSolReserves:   uint64(math.Round(vSol * LamportsPerSol)),
+		TokenTotalSupply:     InitialTokenTotalSupply,
+	}
+
+	if offset := uint64(InitialVirtualTokenReserves - InitialRealTokenReserves); bc.VirtualTokenReserves > offset {
+		bc.RealTokenReserves = bc.VirtualTokenReserves - offset
+	}
+	if bc.VirtualSolReserves > InitialVirtualSolReserves {
+		bc.RealSolReserves = bc.VirtualSolReserves - InitialVirtualSolReserves
+	}
+	bc.Complete = bc.RealTokenReserves == 0
+
+	return bc
+}
+
+func PriceInSolFromBondingCurveAddress(ctx context.Context, client *rpc.Client, bondingCurveAddress string) (float64, error) {
+	mint, err := client.GetAccountInfoWithOpts(ctx, solana.MPK(bondingCurveAddress), &rpc.GetAccountInfoOpts{Commitment: rpc.CommitmentConfirmed})
 
 	if err != nil {
 		return 0, err
 	}
 
+	if mint.Value == nil {
+		return 0, errors.New("bonding curve account not found")
+	}
+
 	DataBytes := mint.Value.Data.GetBinary()
 	Data, err := DecodeBondingData(DataBytes)
 	if err != nil {
 		return 0, err
 	}
 
-	solTokenPrice := float64(float64(Data.VirtualSolReserves/solana.LAMPORTS_PER_SOL)) / float64(Data.VirtualTokenReserves) * 1000000
-	solTokenPrice = math.Round(solTokenPrice*1e9) / 1e9
+	return Data.SpotPrice(), nil
+}
+
+// QuoteBuy returns the raw token amount lamports buys, fee included in lamports
+func (bc *BondingCurve) QuoteBuy(lamports uint64) uint64 {
+	if bc.Complete || bc.VirtualSolReserves == 0 || lamports == 0 {
+		return 0
+	}
+
+	solIn := mulDiv(lamports, 10_000, 10_000+FeeBasisPoints)
+	if solIn == 0 {
+		return 0
+	}
+	k := new(big.Int).Mul(u128(bc.VirtualTokenReserves), u128(bc.VirtualSolReserves))
+	tokensLeft := k.Quo(k, u128(bc.VirtualSolReserves+solIn)).Uint64()
+	// too little sol to move the curve by more than the rounding unit, the subtraction below would wrap
+	if tokensLeft+1 >= bc.VirtualTokenReserves {
+		return 0
+	}
+	tokens := bc.VirtualTokenReserves - tokensLeft - 1
+
+	return min(tokens, bc.RealTokenReserves)
+}
+
+// BuyCost returns the lamports, fee included, the program charges for tokens.
+// It fails for more tokens than the curve has left, or a cost that doesn't fit in lamports.
+func (bc *BondingCurve) BuyCost(tokens uint64) (uint64, error) {
+	if tokens > bc.RealTokenReserves || tokens >= bc.VirtualTokenReserves {
+		return 0, fmt.Errorf("buying %d tokens, the curve has %d left", tokens, bc.RealTokenReserves)
+	}
+
+	cost := new(big.Int).Mul(u128(tokens), u128(bc.VirtualSolReserves))
+	cost.Quo(cost, u128(bc.VirtualTokenReserves-tokens))
+	cost.Add(cost, big.NewInt(1))
+	fee := new(big.Int).Mul(cost, big.NewInt(FeeBasisPoints))
+	cost.Add(cost, fee.Quo(fee, big.NewInt(10_000)))
+	if !cost.IsUint64() {
+		return 0, fmt.Errorf("cost of buying %d tokens overflows", tokens)
+	}
+	return cost.Uint64(), nil
+}
+
+// QuoteSell returns the lamports received for selling raw tokens, after the fee
+func (bc *BondingCurve) QuoteSell(tokens uint64) uint64 {
+	if bc.Complete {
+		return 0
+	}
+
+	output := mulDiv(tokens, bc.VirtualSolReserves, bc.VirtualTokenReserves+tokens)
+	return output - output*FeeBasisPoints/10_000
+}
+
+// SpotPrice is the marginal price in sol of one whole token
+func (bc *BondingCurve) SpotPrice() float64 {
+	if bc.VirtualTokenReserves == 0 {
+		return 0
+	}
+
+	sol := new(big.Float).SetUint64(bc.VirtualSolReserves)
+	sol.Quo(sol, big.NewFloat(LamportsPerSol))
+	tokens := new(big.Float).SetUint64(bc.VirtualTokenReserves)
+	tokens.Quo(tokens, big.NewFloat(math.Pow10(TokenDecimals)))
+	price, _ := sol.Quo(sol, tokens).Float64()
+
+	return price
+}
+
+// PriceImpact is how far in percent the average fill for a buy of lamports sits above spot
+func (bc *BondingCurve) PriceImpact(lamports uint64) float64 {
+	tokens := bc.QuoteBuy(lamports)
+	spot := bc.SpotPrice()
+	if tokens == 0 || spot == 0 {
+		return 0
+	}
+
+	fillPrice := (float64(lamports) / LamportsPerSol) / (float64(tokens) / math.Pow10(TokenDecimals))
+	return (fillPrice - spot) / spot * 100
+}
 
-	return solTokenPrice, nil
+// SolToGraduate is the lamports, fee included, needed to buy out the real token reserves
+func (bc *BondingCurve) SolToGraduate() (uint64, error) {
+	if bc.Complete {
+		return 0, nil
+	}
+	return bc.BuyCost(bc.RealTokenReserves)
+}
+
+func (bc *BondingCurve) MarketCapSol() float64 {
+	return bc.SpotPrice() * float64(bc.TokenTotalSupply) / math.Pow10(TokenDecimals)
+}
+
+// RawTokens converts whole tokens to raw token units, rounding off the float error, negatives are 0
+func RawTokens(amount float64) uint64 {
+	if amount <= 0 {
+		return 0
+	}
+	return uint64(math.Round(amount * math.Pow10(TokenDecimals)))
+}
+
+// WholeTokens converts raw token units to whole tokens
+func WholeTokens(raw uint64) float64 {
+	return float64(raw) / math.Pow10(TokenDecimals)
+}
+
+func u128(n uint64) *big.Int {
+	return new(big.Int).SetUint64(n)
+}
+
+// a*b/c without overflowing, rounded down like the on-chain u128 math, only for b <= c or a <= c where the result fits
+func mulDiv(a, b, c uint64) uint64 {
+	if c == 0 {
+		return 0
+	}
+	n := new(big.Int).Mul(u128(a), u128(b))
+	return n.Quo(n, u128(c)).Uint64()
 }
@@ -0,0 +1,89 @@
+package pumpfun
+
+import (
+	"math"
+	"testing"
+)
+
+func TestQuoteBuy(t *testing.T) {
+	launch := BondingCurve{
+		VirtualTokenReserves: InitialVirtualTokenReserves,
+		VirtualSolReserves:   InitialVirtualSolReserves,
+		RealTokenReserves:    InitialRealTokenReserves,
+		TokenTotalSupply:     InitialTokenTotalSupply,
+	}
+
+	tests := []struct {
+		name     string
+		lamports uint64
+		want     uint64
+	}{
+		// 1 lamport is all fee, nothing reaches the curve
+		{"one lamport", 1, 0},
+		// 2 lamports leave 1 after the fee: 1073e12 - floor(1073e12*30e9/(30e9+1)) - 1
+		{"two lamports", 2, 35_766},
+		// 1 sol less the 1% fee is 990099009 lamports into the curve
+		{"one sol", 1_000_000_000, 34_281_150_129_545},
+		{"nothing", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := launch.QuoteBuy(tt.lamports)
+			if got != tt.want {
+				t.Errorf("QuoteBuy(%d) = %d, want %d", tt.lamports, got, tt.want)
+			}
+			if got > launch.RealTokenReserves {
+				t.Errorf("QuoteBuy(%d) = %d, more than the %d real tokens", tt.lamports, got, launch.RealTokenReserves)
+			}
+		})
+	}
+}
+
+func TestBuyCost(t *testing.T) {
+	launch := BondingCurve{
+		VirtualTokenReserves: InitialVirtualTokenReserves,
+		VirtualSolReserves:   InitialVirtualSolReserves,
+		RealTokenReserves:    InitialRealTokenReserves,
+		TokenTotalSupply:     InitialTokenTotalSupply,
+	}
+	// reserves where a near full buy of the virtual tokens costs more than fits in a uint64
+	overflow := BondingCurve{
+		VirtualTokenReserves: 1_000_000,
+		VirtualSolReserves:   math.MaxUint64 / 2,
+		RealTokenReserves:    1_000_000,
+	}
+
+	tests := []struct {
+		name    string
+		curve   BondingCurve
+		tokens  uint64
+		want    uint64
+		wantErr bool
+	}{
+		// the tokens 2 lamports buy cost 1 lamport, the fee on it rounds down to nothing
+		{"two lamports worth", launch, 35_766, 1, false},
+		{"one sol worth", launch, 34_281_150_129_545, 999_999_999, false},
+		{"all the real tokens", launch, InitialRealTokenReserves, 85_855_412_647, false},
+		{"more than the real tokens", launch, InitialRealTokenReserves + 1, 0, true},
+		{"overflow", overflow, 999_999, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.curve.BuyCost(tt.tokens)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("BuyCost(%d) = %d, want an error", tt.tokens, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("BuyCost(%d) = %d, want %d", tt.tokens, got, tt.want)
+			}
+		})
+	}
+}
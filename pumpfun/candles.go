@@ -0,0 +1,82 @@
+package pumpfun
+
+import (
+	"time"
+)
+
+// Series is one mint's OHLCV bars at one interval, built trade by trade.
+// Bars start on multiples of Interval, the last one is still forming until a trade lands in the next.
+type Series struct {
+	Interval time.Duration
+	Keep     int // bars kept, older ones are dropped
+	bars     []Candle
+}
+
+// Add puts a trade at price with volume sol into its bar, bars nothing traded in carry the previous close
+func (s *Series) Add(price, volume float64, at time.Time) {
+	start := at.Truncate(s.Interval)
+
+	if n := len(s.bars); n > 0 {
+		last := &s.bars[n-1]
+		switch {
+		case start.Equal(last.Time):
+			last.High = max(last.High, price)
+			last.Low = min(last.Low, price)
+			last.Close = price
+			last.Volume += volume
+			return
+		case start.Before(last.Time):
+			// out of order, fold it into the forming bar rather than rewrite a closed one
+			last.High = max(last.High, price)
+			last.Low = min(last.Low, price)
+			last.Volume += volume
+			return
+		}
+
+		prev := last.Close
+		gap := int(start.Sub(last.Time)/s.Interval) - 1
+		if s.Keep > 0 {
+			gap = min(gap, s.Keep)
+		}
+		for i := gap; i > 0; i-- {
+			s.bars = append(s.bars, Candle{
+				Open: prev, High: prev, Low: prev, Close: prev,
+				Time: start.Add(-time.Duration(i) * s.Interval),
+			})
+		}
+	}
+
+	s.bars = append(s.bars, Candle{Open: price, High: price, Low: price, Close: price, Volume: volume, Time: start})
+	if s.Keep > 0 && len(s.bars) > s.Keep {
+		s.bars = append(s.bars[:0], s.bars[len(s.bars)-s.Keep:]...)
+	}
+}
+
+// Last returns up to the last n bars oldest first, n of zero returns every kept bar
+func (s *Series) Last(n int) []Candle {
+	bars := s.bars
+	if n > 0 && len(bars) > n {
+		bars = bars[len(bars)-n:]
+	}
+	return append([]Candle(nil), bars...)
+}
+
+// HeikinAshi smooths candles into Heikin-Ashi bars, the first bar seeds the open with its own open and close
+func HeikinAshi(candles []Candle) []Candle {
+	ha := make([]Candle, len(candles))
+	for i, c := range candles {
+		ha[i] = Candle{
+			Close:  (c.Open + c.High + c.Low + c.Close) / 4,
+			Volume: c.Volume,
+			Time:   c.Time,
+		}
+		if i == 0 {
+			ha[i].Open = (c.Open + c.Close) / 2
+		} else {
+			ha[i].Open = (ha[i-1].Open + ha[i-1].Close) / 2
+		}
+		ha[i].High = max(c.High, ha[i].Open, ha[i].Close)
+		ha[i].Low = min(c.Low, ha[i].Open, ha[i].Close)
+	}
+	return ha
+}
@@ -1,7 +1,9 @@
 package pumpfun
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math"
@@ -16,6 +18,8 @@ import (
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 	"golang.org/x/time/rate"
+	"trader.fun/pumpfun/program"
+	"trader.fun/ta"
 
 	http "github.com/bogdanfinn/fhttp"
 	tls_client "github.com/bogdanfinn/tls-client"
@@ -33,8 +37,25 @@ var (
 		rate.Every(time.Second*10), // time frame
 		35,                         // limit of requests per time frame
 	))
+	prices *PriceFeed
+	trades *Market
 )
 
+// SetRPCClient replaces the mainnet client coins read their curves with, main sets it to the configured endpoint
+func SetRPCClient(client *rpc.Client) {
+	rpcClient = client
+}
+
+// SetPriceFeed makes Coin.Price read from the feed, watching the coin's curve on first use
+func SetPriceFeed(feed *PriceFeed) {
+	prices = feed
+}
+
+// SetMarket makes Coin.Price read a traded coin's curve from the market before trying the price feed
+func SetMarket(market *Market) {
+	trades = market
+}
+
 type PumpWallet string
 
 type Coin struct {
@@ -44,8 +65,66 @@ type Coin struct {
 	MarketCap              float64
 }
 
+// NewCoin derives the coin's curve accounts from its mint alone
+func NewCoin(mint solana.PublicKey) (*Coin, error) {
+	coin := &Coin{MintAddr: mint}
+	if err := coin.Derive(); err != nil {
+		return nil, err
+	}
+	return coin, nil
+}
+
+// Derive fills in whichever curve accounts are missing, they're PDAs of the mint so no api is needed
+func (c *Coin) Derive() error {
+	if c.MintAddr.IsZero() {
+		return errors.New("coin has no mint")
+	}
+	if c.TokenBondingCurve.IsZero() {
+		curve, err := program.BondingCurveAddress(c.MintAddr)
+		if err != nil {
+			return fmt.Errorf("error deriving bonding curve: %v", err)
+		}
+		c.TokenBondingCurve = curve
+	}
+	if c.AssociatedBondingCurve.IsZero() {
+		associated, _, err := solana.FindAssociatedTokenAddress(c.TokenBondingCurve, c.MintAddr)
+		if err != nil {
+			return fmt.Errorf("error deriving associated bonding curve: %v", err)
+		}
+		c.AssociatedBondingCurve = associated
+	}
+	return nil
+}
+
 type Candle struct {
 	High, Low, Open, Close float64
+	Volume                 float64   // sol
+	Time                   time.Time // start of the bar
+}
+
+// IndicatorPeriods are how many bars the Coin indicators look back over
+type IndicatorPeriods struct {
+	RSI        int // price changes, Wilder smoothed past the first period
+	EMA        int
+	SMA        int // also the standard deviation's
+	Volatility int // returns
+	Fib        int // candles the swing is taken over
+}
+
+// Periods fit the 3 one-minute candles Compile feeds the model
+var Periods = IndicatorPeriods{RSI: 2, EMA: 3, SMA: 3, Volatility: 2, Fib: 3}
+
+func closes(candles []Candle) []float64 {
+	out := make([]float64, len(candles))
+	for i, candle := range candles {
+		out[i] = candle.Close
+	}
+	return out
+}
+
+// Bar is the candle as the ta indicators read it
+func (c Candle) Bar() ta.Bar {
+	return ta.Bar{Open: c.Open, High: c.High, Low: c.Low, Close: c.Close, Volume: c.Volume}
 }
 
 type Comment struct {
@@ -54,6 +133,7 @@ type Comment struct {
 	Msg       string
 }
 
+// Compile gathers the model's inputs, it's nil when the coin hasn't got 3 candles yet
 func (c *Coin) Compile() (data []float64) {
 	var (
 		metadata map[string]interface{}
@@ -68,6 +148,11 @@ func (c *Coin) Compile() (data []float64) {
 	go func() { defer wg.Done(); comments = c.Comments() }()
 	wg.Wait()
 
+	// the candle inputs are prices, a coin without 3 real bars can't be scored
+	if len(candles) < 3 {
+		return nil
+	}
+
 	var (
 		dexPaid               bool
 		rugChance             float64
@@ -513,8 +598,6 @@ func (c *Coin) Metadata() map[string]interface{} {
 	metadataMap["hasWebsite"] = metadata["website"] != nil
 	metadataMap["hasTelegram"] = metadata["telegram"] != nil
 
-	c.AssociatedBondingCurve = solana.MPK(metadata["associated_bonding_curve"].(string))
-
 	return metadataMap
 }
 
@@ -558,15 +641,28 @@ func (c *Coin) CommentPositivity(comments []*Comment) float64 {
 }
 
 // heikin ashi candles
+// Candles is the coin's last 3 one-minute Heikin-Ashi bars, built from the market's trades once it has 3 of them
+// and fetched from the frontend api otherwise. It's shorter when neither has 3, bars are never made up.
 func (c *Coin) Candles() []Candle {
-	var ret []Candle
-	for i := 0; i < 3; i++ {
-		ret = append(ret, Candle{})
+	var candles []Candle
+	if trades != nil {
+		candles = trades.Candles(c.MintAddr, time.Minute, 0)
+	}
+	if len(candles) < 3 {
+		candles = c.fetchCandles()
+	}
+
+	candles = HeikinAshi(candles)
+	if len(candles) > 3 {
+		candles = candles[len(candles)-3:]
 	}
+	return candles
+}
 
+func (c *Coin) fetchCandles() []Candle {
 	req, err := http.NewRequest("GET", "https://frontend-api-v3.pump.fun/candlesticks/"+c.MintAddr.String()+"?offset=0&limit=3&timeframe=1", nil)
 	if err != nil {
-		return ret
+		return nil
 	}
 
 	req.Header = http.Header{
@@ -575,18 +671,18 @@ func (c *Coin) Candles() []Candle {
 
 	resp, err := netClient.Do(req)
 	if err != nil {
-		return ret
+		return nil
 	}
 	defer resp.Body.Close()
 
 	body, _ := ioutil.ReadAll(resp.Body)
 	if len(body) == 0 {
-		return ret
+		return nil
 	}
 
 	type Candles []struct {
 		Mint      string  `json:"mint"`
-		Timestamp int     `json:"timestamp"`
+		Timestamp int64   `json:"timestamp"`
 		Open      float64 `json:"open"`
 		High      float64 `json:"high"`
 		Low       float64 `json:"low"`
@@ -600,64 +696,15 @@ func (c *Coin) Candles() []Candle {
 	var candles Candles
 
 	if err := json.Unmarshal(body, &candles); err != nil {
-		return ret
-	}
-
-	countLeadingZeros := func(n float64) int {
-		nStr := strconv.FormatFloat(n, 'f', -1, 64)
-		zeroCount := 0
-		for i := 2; i < len(nStr); i++ { // Skip "0."
-			if nStr[i] == '0' {
-				zeroCount++
-			} else {
-				break
-			}
-		}
-		return zeroCount
-	}
-
-	adjustNumber := func(n float64) float64 {
-		if n >= 1.0 {
-			return n // No adjustment needed
-		}
-
-		if countLeadingZeros(n) > 5 {
-			return n * 100000 // Move it two decimal places
-		}
-
-		if countLeadingZeros(n) > 3 {
-			return n * 100 // Move it two decimal places
-		}
-
-		return n
+		return nil
 	}
 
-	var haCandles []Candle
-	for i, c := range candles {
-		var haCandle Candle
-		haCandle.Close = adjustNumber((c.Open + c.High + c.Low + c.Close) / 4)
-
-		if i == 0 {
-			haCandle.Open = adjustNumber(c.Open)
-		} else {
-			haCandle.Open = adjustNumber((haCandles[i-1].Open + haCandles[i-1].Close) / 2)
-		}
-
-		haCandle.High = adjustNumber(max(c.High, haCandle.Open, haCandle.Close))
-		haCandle.Low = adjustNumber(min(c.Low, haCandle.Open, haCandle.Close))
-
-		haCandles = append(haCandles, haCandle)
-	}
-
-	if len(haCandles) < 3 {
-		var toAdd []Candle
-		for i := 0; i < 3-len(haCandles); i++ {
-			toAdd = append(toAdd, Candle{})
-		}
-		haCandles = append(toAdd, haCandles...)
+	// the api doesn't say what unit its volume is in, so it's left out
+	var ret []Candle
+	for _, c := range candles {
+		ret = append(ret, Candle{Open: c.Open, High: c.High, Low: c.Low, Close: c.Close, Time: time.Unix(c.Timestamp, 0)})
 	}
-
-	return haCandles
+	return ret
 }
 
 // 1s%, 2s%, 3s%
@@ -674,107 +721,52 @@ func (c *Coin) CandlesMP(candles []Candle) []float64 {
 }
 
 func (c *Coin) EMA(candles []Candle) []float64 {
-	var emaValues []float64
-	period := len(candles)
-
-	// Calculate the multiplier for EMA
-	multiplier := 2.0 / (float64(period) + 1)
-
-	// Calculate the EMA for each candle
-	ema := candles[0].Close
-	for i := 0; i < len(candles); i++ {
-		ema = ((candles[i].Close - ema) * multiplier) + ema
-		emaValues = append(emaValues, ema)
-	}
-	return emaValues
+	return ta.Series(ta.NewEMA(Periods.EMA), closes(candles))
 }
 
+// StandardDeviation is the population standard deviation of the last Periods.SMA closes, capped at 1
 func (c *Coin) StandardDeviation(candles []Candle) float64 {
-	if len(candles) == 0 {
-		return 0
-	}
-
-	// Calculate the mean (average) of the close prices
-	var sum float64
-	for _, candle := range candles {
-		sum += candle.Close
+	bands := ta.NewBollinger(Periods.SMA, 0)
+	for _, price := range closes(candles) {
+		bands.Update(price)
 	}
-	mean := sum / float64(len(candles))
-
-	// Calculate the variance
-	var varianceSum float64
-	for _, candle := range candles {
-		diff := candle.Close - mean
-		varianceSum += diff * diff
-	}
-	variance := varianceSum / float64(len(candles))
-	sd := math.Sqrt(variance)
-	if sd > 1 {
-		sd = 1
-	}
-	if sd < 0 {
-		sd = 0
-	}
-	// Return the square root of the variance (standard deviation)
-	return sd
+	return min(bands.StdDev(), 1)
 }
 
+// Volatility is the standard deviation of the last Periods.Volatility returns, capped at 1.
+// It's 0 until there are enough candles.
 func (c *Coin) Volatility(candles []Candle) float64 {
-	returns := make([]float64, 2)
-	for i := 0; i < 2; i++ {
-		returns[i] = (candles[i+1].Close - candles[i].Close) / candles[i].Close
-	}
-
-	// Calculate standard deviation of returns
-	mean := (returns[0] + returns[1]) / 2
-	var sumSquares float64
-	for _, r := range returns {
-		sumSquares += (r - mean) * (r - mean)
-	}
-
-	volatility := math.Sqrt(sumSquares / float64(len(returns)))
-	if volatility > 1 {
-		volatility = 1
+	if len(candles) < Periods.Volatility+1 {
+		return 0
 	}
-	if volatility < 0 {
-		volatility = 0
+	returns := ta.NewBollinger(Periods.Volatility, 0)
+	for i := 1; i < len(candles); i++ {
+		if candles[i-1].Close == 0 {
+			return 0
+		}
+		returns.Update((candles[i].Close - candles[i-1].Close) / candles[i-1].Close)
 	}
-	return volatility
+	return min(returns.StdDev(), 1)
 }
 
+// RSI is Wilder's RSI over Periods.RSI changes scaled to 0..1, 0.5 until there are enough candles
 func (c *Coin) RSI(candles []Candle) float64 {
-	period := len(candles)
-
-	// Calculate the gains and losses over the period
-	var gains, losses float64
-	for i := 1; i < period; i++ {
-		change := candles[i].Close - candles[i-1].Close
-		if change > 0 {
-			gains += change
-		} else {
-			losses -= change
-		}
-	}
-
-	// Calculate the average gain and loss
-	avgGain := gains / float64(period)
-	avgLoss := losses / float64(period)
-
-	// Calculate the Relative Strength (RS)
-	if avgLoss == 0 {
-		return 1
+	rsi := ta.NewRSI(Periods.RSI)
+	for _, price := range closes(candles) {
+		rsi.Update(price)
 	}
-	rs := avgGain / avgLoss
-
-	// Calculate the RSI
-	rsi := 100 - (100 / (1 + rs))
-	return rsi / 100.
+	return rsi.Value() / 100
 }
 
 func (c *Coin) MAR(candles []Candle) float64 {
 	if len(candles) < 2 {
 		return 0
 	}
+	for _, candle := range candles[:len(candles)-1] {
+		if candle.Close == 0 {
+			return 0
+		}
+	}
 
 	// Calculate the total return
 	var totalReturn float64
@@ -810,40 +802,30 @@ func (c *Coin) MAR(candles []Candle) float64 {
 	return c.normalize(mar, -2, 2, 0, 1)
 }
 
+// SMA is the average of the last Periods.SMA closes
 func (c *Coin) SMA(candles []Candle) float64 {
-	var prices []float64
-	for i := 0; i < len(candles); i++ {
-		prices = append(prices, candles[i].Close)
-	}
-	var sum float64
-	for _, price := range prices {
-		sum += price
+	sma := ta.NewSMA(Periods.SMA)
+	for _, price := range closes(candles) {
+		sma.Update(price)
 	}
-	return sum / float64(len(prices))
+	return sma.Value()
 }
 
-// up or down based on fib
+// FibIndicator is whether the swing over the last Periods.Fib candles is up and the last close has pulled back
+// between its 23.6% and 78.6% retracements with the RSI, scaled 0..1 like RSI returns it, under 0.3
 func (c *Coin) FibIndicator(candles []Candle, rsi float64) bool {
-
-	sma := c.SMA(candles)
-	high, low := c.GetHighestAndLowestPrice(candles)
-	fibonacciLevels := c.FibonacciLevels(high, low)
-	lastCandle := candles[len(candles)-1]
-	rsi = rsi * 100
-
-	// buy
-	if lastCandle.Close < fibonacciLevels[0] && rsi < 30 && lastCandle.Close > sma {
-		return true
-	}
-
-	// sell
-	if lastCandle.Close > fibonacciLevels[3] && rsi > 70 && lastCandle.Close < sma {
+	if len(candles) < Periods.Fib {
 		return false
 	}
+	fib := ta.NewFibonacci(Periods.Fib)
+	for _, candle := range candles {
+		fib.Update(candle.Bar())
+	}
 
-	// else hold (but we dont implement for our example)
-
-	return false
+	swing := fib.Value()
+	last := candles[len(candles)-1].Close
+	return swing.Up && swing.Range() > 0 && rsi < 0.3 &&
+		last <= swing.Retracement(0.236) && last > swing.Retracement(0.786)
 }
 
 func (c *Coin) GetHighestAndLowestPrice(candles []Candle) (float64, float64) {
@@ -865,14 +847,9 @@ func (c *Coin) GetHighestAndLowestPrice(candles []Candle) (float64, float64) {
 	return highest, lowest
 }
 
+// FibonacciLevels are the 23.6%, 38.2%, 50%, 61.8% and 100% retracements down from high
 func (c *Coin) FibonacciLevels(high, low float64) []float64 {
-	fibRange := high - low
-	level23_6 := high - fibRange*0.236
-	level38_2 := high - fibRange*0.382
-	level50 := high - fibRange*0.5
-	level61_8 := high - fibRange*0.618
-	level100 := low
-	return []float64{level23_6, level38_2, level50, level61_8, level100}
+	return ta.Swing{High: high, Low: low, Up: true}.Levels([]float64{0.236, 0.382, 0.5, 0.618, 1})
 }
 
 func (c *Coin) Trades() int {
@@ -901,8 +878,21 @@ func (c *Coin) Trades() int {
 	return count
 }
 
+// Price is the coin's spot price from the market or the price feed when either has one, otherwise from the rpc
 func (c *Coin) Price() float64 {
-	price, _ := PriceInSolFromBondingCurveAddress(rpcClient, c.TokenBondingCurve.String())
+	if trades != nil {
+		if price, ok := trades.Price(c.MintAddr); ok {
+			return price
+		}
+	}
+	if prices != nil {
+		if price, ok := prices.Price(c.TokenBondingCurve); ok {
+			return price
+		}
+		prices.Watch(c.TokenBondingCurve)
+	}
+
+	price, _ := PriceInSolFromBondingCurveAddress(context.Background(), rpcClient, c.TokenBondingCurve.String())
 
 	return price
 }
@@ -0,0 +1,108 @@
+package pumpfun
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/codingsandmore/pumpfun-portal/portal"
+	"github.com/gorilla/websocket"
+	"github.com/patrickmn/go-cache"
+)
+
+const pumpPortalURL = "wss://pumpportal.fun/api/data"
+
+type subscription struct {
+	Method string   `json:"method"`
+	Keys   []string `json:"keys,omitempty"`
+}
+
+// discover reads pumpportal until ctx is cancelled, reconnecting on errors
+func (pf *Pumpfun) discover(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := pf.readFeed(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		fmt.Println("Pumpportal:", err)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(1 * time.Second):
+		}
+	}
+}
+
+func (pf *Pumpfun) readFeed(ctx context.Context) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, pumpPortalURL, nil)
+	if err != nil {
+		return fmt.Errorf("error connecting to pumpportal: %v", err)
+	}
+	defer conn.Close()
+
+	// closing the connection is the only way to interrupt a blocked read
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := conn.WriteJSON(subscription{Method: "subscribeNewToken"}); err != nil {
+		return fmt.Errorf("error subscribing to new tokens: %v", err)
+	}
+	// a reconnect would otherwise lose the trades of every pair seen so far
+	if mints := keys(pf.tracked); len(mints) > 0 {
+		if err := conn.WriteJSON(subscription{Method: "subscribeTokenTrade", Keys: mints}); err != nil {
+			return fmt.Errorf("error subscribing to trades: %v", err)
+		}
+	}
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("error reading pumpportal: %v", err)
+		}
+
+		var kind struct {
+			TxType    string `json:"txType"`
+			Signature string `json:"signature"`
+		}
+		// subscription acks and errors have no signature
+		if err := json.Unmarshal(msg, &kind); err != nil || len(kind.Signature) == 0 {
+			continue
+		}
+
+		if kind.TxType == "create" {
+			var pair portal.NewPairResponse
+			if err := json.Unmarshal(msg, &pair); err != nil {
+				continue
+			}
+
+			pf.tracked.Set(pair.Mint, true, cache.DefaultExpiration)
+			if err := conn.WriteJSON(subscription{Method: "subscribeTokenTrade", Keys: []string{pair.Mint}}); err != nil {
+				return fmt.Errorf("error subscribing to trades: %v", err)
+			}
+
+			// pair handlers may be slow, don't hold up the feed
+			pf.wg.Add(1)
+			go func() {
+				defer pf.wg.Done()
+				pf.discoverPair(&pair)
+			}()
+			continue
+		}
+
+		var trade portal.NewTradeResponse
+		if err := json.Unmarshal(msg, &trade); err != nil {
+			continue
+		}
+		pf.discoverTrade(&trade)
+	}
+}
+
+func keys(c *cache.Cache) []string {
+	items := c.Items()
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,385 @@
+package pumpfun
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/codingsandmore/pumpfun-portal/portal"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"trader.fun/pumpfun/program"
+)
+
+// maxAccountsPerCall is the most accounts getMultipleAccounts takes
+const maxAccountsPerCall = 100
+
+// Trade is one pumpportal trade event as the market keeps it
+type Trade struct {
+	Signature string
+	Trader    string
+	Buy       bool
+	Tokens    float64 // whole tokens
+	Sol       float64 // sol that moved through the curve, before the fee
+	Price     float64 // spot price after the trade
+	MarketCap float64 // sol
+	Time      time.Time
+}
+
+// MarketStats sums a mint's trades over a window
+type MarketStats struct {
+	Trades     int
+	Buys       int
+	Sells      int
+	Traders    int // unique wallets
+	BuyVolume  float64
+	SellVolume float64
+}
+
+func (s MarketStats) Volume() float64 {
+	return s.BuyVolume + s.SellVolume
+}
+
+// NetVolume is the sol bought minus the sol sold
+func (s MarketStats) NetVolume() float64 {
+	return s.BuyVolume - s.SellVolume
+}
+
+// Market rebuilds every traded mint's bonding curve from the reserves pumpportal sends with each trade,
+// so prices and curve quotes are answered from memory without any network call.
+// It keeps each mint's trades for Window, and at least the last Recent of them.
+// It also builds OHLCV bars of the spot price at each of Intervals, keeping the last Bars of each.
+// Mints that haven't traded for Idle are dropped, zero keeps them for good.
+// Intervals and Bars are read when a mint first trades, set them before feeding trades.
+type Market struct {
+	Window    time.Duration
+	Recent    int
+	Intervals []time.Duration
+	Bars      int
+	Idle      time.Duration
+	// Client, when set, lets Run read the curves of up to VerifyBatch mints in one call every Verify, the least recently
+	// checked first, to catch the event reserves drifting from the chain. It's usually the rate limited client orders
+	// go through too, so verification never makes more than that one call per Verify.
+	Client      *rpc.Client
+	Verify      time.Duration
+	VerifyBatch int     // at most 100, the most accounts getMultipleAccounts takes
+	Drift       float64 // percent the spot price may differ from the chain's before the chain's curve replaces ours
+
+	mints map[solana.PublicKey]*market
+	lock  sync.Mutex
+}
+
+type market struct {
+	curve    *BondingCurve
+	trades   []Trade
+	series   map[time.Duration]*Series
+	updated  time.Time
+	verified time.Time
+	version  uint64 // bumped on every trade, a chain read is stale if it changed meanwhile
+}
+
+// OnTrade updates the mint's curve as of now, it has the signature of a pumpfun trade handler
+func (m *Market) OnTrade(p *portal.NewTradeResponse) {
+	m.Update(p, time.Now())
+}
+
+// Update applies a trade event received at now, the backtester passes the tape's time
+func (m *Market) Update(p *portal.NewTradeResponse, now time.Time) {
+	if p.VTokensInBondingCurve <= 0 {
+		return
+	}
+	mint, err := solana.PublicKeyFromBase58(p.Mint)
+	if err != nil {
+		return
+	}
+
+	curve := NewBondingCurveFromReserves(p.VTokensInBondingCurve, p.VSolInBondingCurve)
+	trade := Trade{
+		Signature: p.Signature,
+		Trader:    p.TraderPublicKey,
+		Buy:       p.TxType == "buy",
+		Tokens:    p.TokenAmount,
+		Sol:       solTraded(p),
+		Price:     curve.SpotPrice(),
+		MarketCap: p.MarketCapSol,
+		Time:      now,
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	state, ok := m.mints[mint]
+	if !ok {
+		state = &market{verified: now, series: make(map[time.Duration]*Series, len(m.Intervals))}
+		for _, interval := range m.Intervals {
+			state.series[interval] = &Series{Interval: interval, Keep: m.Bars}
+		}
+		m.mints[mint] = state
+	}
+	for _, series := range state.series {
+		series.Add(trade.Price, trade.Sol, now)
+	}
+	state.curve = curve
+	state.updated = now
+	state.version++
+	state.trades = append(state.trades, trade)
+	state.prune(now, m.Window, m.Recent)
+}
+
+// Curve is a copy of the mint's curve as of its last trade, ok is false until it has traded
+func (m *Market) Curve(mint solana.PublicKey) (curve BondingCurve, ok bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	state, ok := m.mints[mint]
+	if !ok {
+		return curve, false
+	}
+	return *state.curve, true
+}
+
+func (m *Market) Price(mint solana.PublicKey) (float64, bool) {
+	curve, ok := m.Curve(mint)
+	if !ok {
+		return 0, false
+	}
+	return curve.SpotPrice(), true
+}
+
+// QuoteBuy is the raw tokens lamports buys at the mint's current curve
+func (m *Market) QuoteBuy(mint solana.PublicKey, lamports uint64) (uint64, bool) {
+	curve, ok := m.Curve(mint)
+	if !ok {
+		return 0, false
+	}
+	return curve.QuoteBuy(lamports), true
+}
+
+// QuoteSell is the lamports selling raw tokens returns at the mint's current curve
+func (m *Market) QuoteSell(mint solana.PublicKey, tokens uint64) (uint64, bool) {
+	curve, ok := m.Curve(mint)
+	if !ok {
+		return 0, false
+	}
+	return curve.QuoteSell(tokens), true
+}
+
+// Trades returns up to the last n trades of the mint, oldest first
+func (m *Market) Trades(mint solana.PublicKey, n int) []Trade {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	state, ok := m.mints[mint]
+	if !ok {
+		return nil
+	}
+	trades := state.trades
+	if n > 0 && len(trades) > n {
+		trades = trades[len(trades)-n:]
+	}
+	return append([]Trade(nil), trades...)
+}
+
+// Candles returns up to the last n bars of the mint at interval oldest first, the last still forming.
+// It's nil for a mint that hasn't traded or an interval the market doesn't build.
+func (m *Market) Candles(mint solana.PublicKey, interval time.Duration, n int) []Candle {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	state, ok := m.mints[mint]
+	if !ok {
+		return nil
+	}
+	series, ok := state.series[interval]
+	if !ok {
+		return nil
+	}
+	return series.Last(n)
+}
+
+// Stats sums the mint's trades at or after since, trades older than Window are no longer kept
+func (m *Market) Stats(mint solana.PublicKey, since time.Time) (stats MarketStats) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	state, ok := m.mints[mint]
+	if !ok {
+		return
+	}
+
+	traders := make(map[string]struct{})
+	for _, trade := range state.trades {
+		if trade.Time.Before(since) {
+			continue
+		}
+		stats.Trades++
+		if trade.Buy {
+			stats.Buys++
+			stats.BuyVolume += trade.Sol
+		} else {
+			stats.Sells++
+			stats.SellVolume += trade.Sol
+		}
+		traders[trade.Trader] = struct{}{}
+	}
+	stats.Traders = len(traders)
+	return
+}
+
+// Run drops idle mints and, with a Client, cross-checks a batch of mints against the chain each Verify until ctx is cancelled
+func (m *Market) Run(ctx context.Context) {
+	interval := m.Idle / 2
+	if m.Client != nil && m.Verify > 0 && (interval <= 0 || m.Verify < interval) {
+		interval = m.Verify
+	}
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if due := m.sweep(now); len(due) > 0 {
+				m.verify(ctx, due)
+			}
+		}
+	}
+}
+
+// sweep forgets idle mints and returns up to VerifyBatch of the ones due a chain check, least recently checked first
+func (m *Market) sweep(now time.Time) []solana.PublicKey {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var due []solana.PublicKey
+	for mint, state := range m.mints {
+		if m.Idle > 0 && now.Sub(state.updated) > m.Idle {
+			delete(m.mints, mint)
+			continue
+		}
+		if m.Client != nil && m.Verify > 0 && now.Sub(state.verified) >= m.Verify {
+			due = append(due, mint)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return m.mints[due[i]].verified.Before(m.mints[due[j]].verified)
+	})
+	if batch := min(max(m.VerifyBatch, 1), maxAccountsPerCall); len(due) > batch {
+		due = due[:batch]
+	}
+	for _, mint := range due {
+		m.mints[mint].verified = now
+	}
+	return due
+}
+
+// verify reads the mints' curves from the rpc in one call and takes each over ours when the prices drifted apart
+func (m *Market) verify(ctx context.Context, mints []solana.PublicKey) {
+	var checked, bondingCurves []solana.PublicKey
+	var versions []uint64
+
+	m.lock.Lock()
+	for _, mint := range mints {
+		state, ok := m.mints[mint]
+		bondingCurve, err := program.BondingCurveAddress(mint)
+		if !ok || err != nil {
+			continue
+		}
+		checked = append(checked, mint)
+		bondingCurves = append(bondingCurves, bondingCurve)
+		versions = append(versions, state.version)
+	}
+	m.lock.Unlock()
+	if len(checked) == 0 {
+		return
+	}
+
+	accounts, err := m.Client.GetMultipleAccountsWithOpts(ctx, bondingCurves, &rpc.GetMultipleAccountsOpts{
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		fmt.Println("Market: error verifying curves:", err)
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for i, account := range accounts.Value {
+		if i >= len(checked) || account == nil {
+			continue
+		}
+		data := account.Data.GetBinary()
+		if len(data) < 8 || !bytes.Equal(data[:8], program.BondingCurveAccountDiscriminator[:]) {
+			continue
+		}
+		chain, err := DecodeBondingData(data)
+		if err != nil {
+			continue
+		}
+
+		// a trade landed while reading, the chain's curve may already be behind ours
+		state, ok := m.mints[checked[i]]
+		if !ok || state.version != versions[i] {
+			continue
+		}
+
+		ours, theirs := state.curve.SpotPrice(), chain.SpotPrice()
+		if theirs == 0 {
+			continue
+		}
+		if drift := math.Abs(ours-theirs) / theirs * 100; drift > m.Drift {
+			fmt.Printf("Market: %s drifted %.2f%% from the chain, resyncing\n", checked[i], drift)
+			state.curve = chain
+		}
+	}
+}
+
+// prune drops trades older than window while keeping at least the last recent
+func (s *market) prune(now time.Time, window time.Duration, recent int) {
+	drop := 0
+	for drop < len(s.trades)-recent && now.Sub(s.trades[drop].Time) > window {
+		drop++
+	}
+	if drop > 0 {
+		s.trades = append(s.trades[:0], s.trades[drop:]...)
+	}
+}
+
+// solTraded recovers the sol a trade moved through the curve from the reserves after it,
+// the curve is constant product so the reserves before it follow from the token amount
+func solTraded(p *portal.NewTradeResponse) float64 {
+	k := p.VSolInBondingCurve * p.VTokensInBondingCurve
+	if p.TxType == "buy" {
+		return p.VSolInBondingCurve - k/(p.VTokensInBondingCurve+p.TokenAmount)
+	}
+	if before := p.VTokensInBondingCurve - p.TokenAmount; before > 0 {
+		return k/before - p.VSolInBondingCurve
+	}
+	return 0
+}
+
+func NewMarket(client *rpc.Client) *Market {
+	return &Market{
+		Window:      5 * time.Minute,
+		Recent:      50,
+		Intervals:   []time.Duration{time.Second, 5 * time.Second, 15 * time.Second, time.Minute},
+		Bars:        300,
+		Idle:        30 * time.Minute,
+		Client:      client,
+		Verify:      time.Minute,
+		VerifyBatch: maxAccountsPerCall,
+		Drift:       1,
+		mints:       make(map[solana.PublicKey]*market),
+	}
+}
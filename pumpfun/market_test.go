@@ -0,0 +1,41 @@
+package pumpfun
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func TestSweepVerifiesInBatches(t *testing.T) {
+	m := NewMarket(rpc.New("http://localhost:8899"))
+	start := time.Now()
+	for i := 0; i < 250; i++ {
+		m.mints[solana.NewWallet().PublicKey()] = &market{updated: start, verified: start.Add(time.Duration(i) * time.Millisecond)}
+	}
+
+	seen := make(map[solana.PublicKey]bool)
+	now := start.Add(m.Verify + time.Second)
+	for sweep, want := range []int{100, 100, 50, 0} {
+		due := m.sweep(now)
+		if len(due) != want {
+			t.Fatalf("sweep %d returned %d mints, want %d", sweep, len(due), want)
+		}
+		for _, mint := range due {
+			if seen[mint] {
+				t.Fatalf("sweep %d returned %s again", sweep, mint)
+			}
+			seen[mint] = true
+		}
+	}
+
+	// the least recently verified go first
+	m.mints[solana.NewWallet().PublicKey()] = &market{updated: now, verified: now}
+	oldest := solana.NewWallet().PublicKey()
+	m.mints[oldest] = &market{updated: now, verified: start}
+	m.VerifyBatch = 1
+	if due := m.sweep(now.Add(m.Verify)); len(due) != 1 || due[0] != oldest {
+		t.Errorf("sweep returned %v, want only %s", due, oldest)
+	}
+}
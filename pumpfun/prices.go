@@ -1 +1,350 @@
 package pumpfun
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+// CurveUpdate is a bonding curve as of a slot
+type CurveUpdate struct {
+	BondingCurve solana.PublicKey
+	Curve        *BondingCurve
+	Slot         uint64
+	Time         time.Time
+}
+
+func (u CurveUpdate) Price() float64 {
+	return u.Curve.SpotPrice()
+}
+
+// PriceFeed keeps the latest state of the bonding curves it watches from accountSubscribe notifications,
+// so prices are read from memory instead of an rpc call each.
+// Curves nobody has watched or read for Idle are dropped, zero keeps them until they're unwatched.
+type PriceFeed struct {
+	Endpoint string      // websocket endpoint
+	Client   *rpc.Client // reads a curve's first state, before its first notification
+	Idle     time.Duration
+
+	conn        *ws.Client
+	dialed      *ws.Client // connected by Connect, Run starts on it
+	watched     map[solana.PublicKey]*watch
+	latest      map[solana.PublicKey]CurveUpdate
+	subscribers map[chan CurveUpdate]struct{}
+	dropped     chan struct{}
+	lock        sync.Mutex
+}
+
+type watch struct {
+	sub  *ws.AccountSubscription // nil while disconnected
+	used time.Time
+}
+
+// Watch starts following a curve, watching one that's already followed only keeps it from going idle
+func (f *PriceFeed) Watch(bondingCurve solana.PublicKey) {
+	f.lock.Lock()
+	if w, ok := f.watched[bondingCurve]; ok {
+		w.used = time.Now()
+		f.lock.Unlock()
+		return
+	}
+	f.watched[bondingCurve] = &watch{used: time.Now()}
+	conn := f.conn
+	f.lock.Unlock()
+
+	go f.snapshot(bondingCurve)
+	if conn != nil {
+		go f.subscribe(conn, bondingCurve)
+	}
+}
+
+// Unwatch stops following a curve and forgets its state
+func (f *PriceFeed) Unwatch(bondingCurve solana.PublicKey) {
+	f.lock.Lock()
+	w, ok := f.watched[bondingCurve]
+	delete(f.watched, bondingCurve)
+	delete(f.latest, bondingCurve)
+	f.lock.Unlock()
+
+	if ok && w.sub != nil {
+		w.sub.Unsubscribe()
+	}
+}
+
+// Latest is the last known state of a watched curve, ok is false until the first one arrives
+func (f *PriceFeed) Latest(bondingCurve solana.PublicKey) (update CurveUpdate, ok bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if w, watched := f.watched[bondingCurve]; watched {
+		w.used = time.Now()
+	}
+	update, ok = f.latest[bondingCurve]
+	return
+}
+
+// Price is the latest spot price of a watched curve
+func (f *PriceFeed) Price(bondingCurve solana.PublicKey) (float64, bool) {
+	update, ok := f.Latest(bondingCurve)
+	if !ok {
+		return 0, false
+	}
+	return update.Price(), true
+}
+
+// Subscribe returns a channel of every update to every watched curve and a function to stop them.
+// A subscriber that falls behind misses updates rather than holding up the feed.
+func (f *PriceFeed) Subscribe() (<-chan CurveUpdate, func()) {
+	updates := make(chan CurveUpdate, 64)
+
+	f.lock.Lock()
+	f.subscribers[updates] = struct{}{}
+	f.lock.Unlock()
+
+	var once sync.Once
+	return updates, func() {
+		once.Do(func() {
+			f.lock.Lock()
+			delete(f.subscribers, updates)
+			f.lock.Unlock()
+			close(updates)
+		})
+	}
+}
+
+// Connect dials the websocket once so an endpoint without one fails at startup, Run alone would retry it forever.
+// Run then starts on this connection.
+func (f *PriceFeed) Connect(ctx context.Context) error {
+	conn, err := ws.Connect(ctx, f.Endpoint)
+	if err != nil {
+		return fmt.Errorf("error connecting to the websocket at %s: %v", f.Endpoint, err)
+	}
+
+	f.lock.Lock()
+	if f.dialed != nil {
+		f.dialed.Close()
+	}
+	f.dialed = conn
+	f.lock.Unlock()
+	return nil
+}
+
+// Run keeps the websocket connected and every watched curve subscribed until ctx is cancelled
+func (f *PriceFeed) Run(ctx context.Context) {
+	var janitor <-chan time.Time
+	if f.Idle > 0 {
+		ticker := time.NewTicker(f.Idle / 2)
+		defer ticker.Stop()
+		janitor = ticker.C
+	}
+
+	for ctx.Err() == nil {
+		f.lock.Lock()
+		conn := f.dialed
+		f.dialed = nil
+		f.lock.Unlock()
+		if conn == nil {
+			var err error
+			if conn, err = ws.Connect(ctx, f.Endpoint); err != nil {
+				fmt.Println("Price feed: error connecting to", f.Endpoint+":", err, "- prices are stale until it connects")
+				f.wait(ctx, 5*time.Second)
+				continue
+			}
+		}
+
+		f.lock.Lock()
+		f.conn = conn
+		// forget a drop the last connection signalled on its way out
+		select {
+		case <-f.dropped:
+		default:
+		}
+		curves := make([]solana.PublicKey, 0, len(f.watched))
+		for curve := range f.watched {
+			curves = append(curves, curve)
+		}
+		f.lock.Unlock()
+
+		for _, curve := range curves {
+			f.subscribe(conn, curve)
+		}
+
+	connected:
+		for {
+			select {
+			case <-ctx.Done():
+				break connected
+			case <-f.dropped:
+				fmt.Println("Price feed: connection lost, reconnecting")
+				break connected
+			case <-janitor:
+				f.expire()
+			}
+		}
+
+		f.lock.Lock()
+		f.conn = nil
+		for _, w := range f.watched {
+			w.sub = nil
+		}
+		f.lock.Unlock()
+		conn.Close()
+
+		f.wait(ctx, time.Second)
+	}
+}
+
+func (f *PriceFeed) subscribe(conn *ws.Client, bondingCurve solana.PublicKey) {
+	sub, err := conn.AccountSubscribeWithOpts(bondingCurve, rpc.CommitmentProcessed, solana.EncodingBase64)
+	if err != nil {
+		fmt.Println("Price feed: error subscribing to", bondingCurve, err)
+		f.lock.Lock()
+		current := f.conn == conn
+		f.lock.Unlock()
+		if current {
+			f.drop()
+		}
+		return
+	}
+
+	f.lock.Lock()
+	w, ok := f.watched[bondingCurve]
+	if !ok || f.conn != conn {
+		// unwatched or reconnected while subscribing
+		f.lock.Unlock()
+		sub.Unsubscribe()
+		return
+	}
+	w.sub = sub
+	f.lock.Unlock()
+
+	go f.read(bondingCurve, sub)
+}
+
+func (f *PriceFeed) read(bondingCurve solana.PublicKey, sub *ws.AccountSubscription) {
+	for {
+		result, err := sub.Recv(context.Background())
+		if err != nil || result == nil {
+			f.lock.Lock()
+			w, ok := f.watched[bondingCurve]
+			current := ok && w.sub == sub
+			f.lock.Unlock()
+			// an unsubscribed curve closes its own stream, anything else is the connection going
+			if current {
+				f.drop()
+			}
+			return
+		}
+		if result.Value.Data == nil {
+			continue
+		}
+
+		curve, err := DecodeBondingData(result.Value.Data.GetBinary())
+		if err != nil {
+			fmt.Println("Price feed: error decoding", bondingCurve, err)
+			continue
+		}
+		f.publish(CurveUpdate{BondingCurve: bondingCurve, Curve: curve, Slot: result.Context.Slot, Time: time.Now()})
+	}
+}
+
+// snapshot reads the curve once so it has a price before anyone trades it
+func (f *PriceFeed) snapshot(bondingCurve solana.PublicKey) {
+	if f.Client == nil {
+		return
+	}
+	result, err := f.Client.GetAccountInfoWithOpts(context.Background(), bondingCurve, &rpc.GetAccountInfoOpts{Commitment: rpc.CommitmentProcessed})
+	if err != nil || result.Value == nil {
+		return
+	}
+	curve, err := DecodeBondingData(result.Value.Data.GetBinary())
+	if err != nil {
+		return
+	}
+	f.publish(CurveUpdate{BondingCurve: bondingCurve, Curve: curve, Slot: result.Context.Slot, Time: time.Now()})
+}
+
+// publish keeps the update unless a later slot is already known, then passes it to the subscribers
+func (f *PriceFeed) publish(update CurveUpdate) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if _, ok := f.watched[update.BondingCurve]; !ok {
+		return
+	}
+	if last, ok := f.latest[update.BondingCurve]; ok && last.Slot > update.Slot {
+		return
+	}
+	f.latest[update.BondingCurve] = update
+
+	for updates := range f.subscribers {
+		select {
+		case updates <- update:
+		default:
+		}
+	}
+}
+
+func (f *PriceFeed) expire() {
+	f.lock.Lock()
+	var idle []solana.PublicKey
+	for curve, w := range f.watched {
+		if time.Since(w.used) > f.Idle {
+			idle = append(idle, curve)
+		}
+	}
+	f.lock.Unlock()
+
+	for _, curve := range idle {
+		f.Unwatch(curve)
+	}
+}
+
+func (f *PriceFeed) drop() {
+	select {
+	case f.dropped <- struct{}{}:
+	default:
+	}
+}
+
+func (f *PriceFeed) wait(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// WebsocketEndpoint guesses the websocket endpoint of an http rpc endpoint by swapping the scheme, which is right for
+// providers that serve both on the same host, port and path. Ones that don't, like a local validator with http on
+// 8899 and the websocket on 8900, need the websocket endpoint given explicitly.
+func WebsocketEndpoint(rpcEndpoint string) (string, error) {
+	endpoint, err := url.Parse(rpcEndpoint)
+	if err != nil {
+		return "", err
+	}
+	switch endpoint.Scheme {
+	case "https":
+		endpoint.Scheme = "wss"
+	case "http":
+		endpoint.Scheme = "ws"
+	}
+	return endpoint.String(), nil
+}
+
+func NewPriceFeed(endpoint string, client *rpc.Client) *PriceFeed {
+	return &PriceFeed{
+		Endpoint:    endpoint,
+		Client:      client,
+		Idle:        5 * time.Minute,
+		watched:     make(map[solana.PublicKey]*watch),
+		latest:      make(map[solana.PublicKey]CurveUpdate),
+		subscribers: make(map[chan CurveUpdate]struct{}),
+		dropped:     make(chan struct{}, 1),
+	}
+}
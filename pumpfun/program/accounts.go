@@ -0,0 +1,106 @@
+package program
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Global is the program's config account
+type Global struct {
+	Initialized bool
+	Authority   solana.PublicKey
+	Params
+}
+
+// DecodeGlobal decodes the Global account data, discriminator included
+func DecodeGlobal(data []byte) (*Global, error) {
+	if len(data) < 8 || !bytes.Equal(data[:8], GlobalAccountDiscriminator[:]) {
+		return nil, errors.New("not a Global account")
+	}
+
+	d := decoder{data: data[8:]}
+	global := &Global{
+		Initialized: d.bool(),
+		Authority:   d.publicKey(),
+	}
+	global.FeeRecipient = d.publicKey()
+	global.InitialVirtualTokenReserves = d.u64()
+	global.InitialVirtualSolReserves = d.u64()
+	global.InitialRealTokenReserves = d.u64()
+	global.TokenTotalSupply = d.u64()
+	global.FeeBasisPoints = d.u64()
+	if d.err != nil {
+		return nil, fmt.Errorf("error decoding Global account: %v", d.err)
+	}
+	return global, nil
+}
+
+// FetchGlobal reads the Global account
+func FetchGlobal(ctx context.Context, client *rpc.Client) (*Global, error) {
+	account, err := client.GetAccountInfoWithOpts(ctx, GlobalAddress, &rpc.GetAccountInfoOpts{Commitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return nil, fmt.Errorf("error getting Global account: %v", err)
+	}
+	if account.Value == nil {
+		return nil, errors.New("Global account not found")
+	}
+	return DecodeGlobal(account.Value.Data.GetBinary())
+}
+
+// decoder reads borsh values in order, the first error sticks and later reads return zero values
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if len(d.data) < n {
+		d.err = errors.New("data too short")
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *decoder) u64() uint64 {
+	b := d.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+func (d *decoder) i64() int64 {
+	return int64(d.u64())
+}
+
+func (d *decoder) bool() bool {
+	b := d.next(1)
+	return b != nil && b[0] != 0
+}
+
+func (d *decoder) publicKey() solana.PublicKey {
+	b := d.next(solana.PublicKeyLength)
+	if b == nil {
+		return solana.PublicKey{}
+	}
+	return solana.PublicKeyFromBytes(b)
+}
+
+func (d *decoder) string() string {
+	b := d.next(4)
+	if b == nil {
+		return ""
+	}
+	return string(d.next(int(binary.LittleEndian.Uint32(b))))
+}
@@ -0,0 +1,125 @@
+package program
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var ErrUnknownEvent = errors.New("unknown event")
+
+type CreateEvent struct {
+	Name         string
+	Symbol       string
+	URI          string
+	Mint         solana.PublicKey
+	BondingCurve solana.PublicKey
+	User         solana.PublicKey
+}
+
+// TradeEvent is emitted by every buy and sell, the reserves are the curve's after the trade
+type TradeEvent struct {
+	Mint                 solana.PublicKey
+	SolAmount            uint64
+	TokenAmount          uint64
+	IsBuy                bool
+	User                 solana.PublicKey
+	Timestamp            int64
+	VirtualSolReserves   uint64
+	VirtualTokenReserves uint64
+}
+
+// CompleteEvent is emitted when a buy takes the last of the curve's tokens
+type CompleteEvent struct {
+	User         solana.PublicKey
+	Mint         solana.PublicKey
+	BondingCurve solana.PublicKey
+	Timestamp    int64
+}
+
+type SetParamsEvent struct {
+	Params
+}
+
+// DecodeEvent decodes one event into a *CreateEvent, *TradeEvent, *CompleteEvent or *SetParamsEvent.
+// data is either a "Program data:" log payload or the data of the program's event self invoke.
+func DecodeEvent(data []byte) (interface{}, error) {
+	data = bytes.TrimPrefix(data, eventIxTag)
+	if len(data) < 8 {
+		return nil, errors.New("event data too short")
+	}
+
+	var disc [8]byte
+	copy(disc[:], data[:8])
+	d := decoder{data: data[8:]}
+
+	var event interface{}
+	switch disc {
+	case createEventDiscriminator:
+		event = &CreateEvent{
+			Name:         d.string(),
+			Symbol:       d.string(),
+			URI:          d.string(),
+			Mint:         d.publicKey(),
+			BondingCurve: d.publicKey(),
+			User:         d.publicKey(),
+		}
+	case tradeEventDiscriminator:
+		event = &TradeEvent{
+			Mint:                 d.publicKey(),
+			SolAmount:            d.u64(),
+			TokenAmount:          d.u64(),
+			IsBuy:                d.bool(),
+			User:                 d.publicKey(),
+			Timestamp:            d.i64(),
+			VirtualSolReserves:   d.u64(),
+			VirtualTokenReserves: d.u64(),
+		}
+	case completeEventDiscriminator:
+		event = &CompleteEvent{
+			User:         d.publicKey(),
+			Mint:         d.publicKey(),
+			BondingCurve: d.publicKey(),
+			Timestamp:    d.i64(),
+		}
+	case setParamsEventDiscriminator:
+		event = &SetParamsEvent{Params{
+			FeeRecipient:                d.publicKey(),
+			InitialVirtualTokenReserves: d.u64(),
+			InitialVirtualSolReserves:   d.u64(),
+			InitialRealTokenReserves:    d.u64(),
+			TokenTotalSupply:            d.u64(),
+			FeeBasisPoints:              d.u64(),
+		}}
+	default:
+		return nil, ErrUnknownEvent
+	}
+
+	if d.err != nil {
+		return nil, fmt.Errorf("error decoding event: %v", d.err)
+	}
+	return event, nil
+}
+
+// EventsFromLogs decodes the events in a transaction's "Program data:" log lines, lines that aren't pump events are skipped
+func EventsFromLogs(logs []string) []interface{} {
+	var events []interface{}
+	for _, line := range logs {
+		payload, ok := strings.CutPrefix(line, "Program data: ")
+		if !ok {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			continue
+		}
+		if event, err := DecodeEvent(data); err == nil {
+			events = append(events, event)
+		}
+	}
+	return events
+}
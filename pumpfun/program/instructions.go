@@ -0,0 +1,182 @@
+package program
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Params are the curve parameters set_params writes to the Global account
+type Params struct {
+	FeeRecipient                solana.PublicKey
+	InitialVirtualTokenReserves uint64
+	InitialVirtualSolReserves   uint64
+	InitialRealTokenReserves    uint64
+	TokenTotalSupply            uint64
+	FeeBasisPoints              uint64
+}
+
+// Create launches a new coin, mint is a fresh keypair that signs alongside user
+func Create(mint, user solana.PublicKey, name, symbol, uri string) (solana.Instruction, error) {
+	curve, err := BondingCurveAddress(mint)
+	if err != nil {
+		return nil, err
+	}
+	associatedCurve, err := AssociatedBondingCurveAddress(mint)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := MetadataAddress(mint)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(mint).WRITE().SIGNER(),
+		solana.Meta(MintAuthority),
+		solana.Meta(curve).WRITE(),
+		solana.Meta(associatedCurve).WRITE(),
+		solana.Meta(GlobalAddress),
+		solana.Meta(MetadataProgramID),
+		solana.Meta(metadata).WRITE(),
+		solana.Meta(user).WRITE().SIGNER(),
+		solana.Meta(solana.SystemProgramID),
+		solana.Meta(solana.TokenProgramID),
+		solana.Meta(solana.SPLAssociatedTokenAccountProgramID),
+		solana.Meta(solana.SysVarRentPubkey),
+		solana.Meta(EventAuthority),
+		solana.Meta(ProgramID),
+	}
+
+	data := instructionData(createDiscriminator, name, symbol, uri)
+	return solana.NewInstruction(ProgramID, accounts, data), nil
+}
+
+// Buy buys amount raw tokens of mint, failing if they'd cost more than maxSolCost lamports including the fee
+func Buy(mint, user, feeRecipient solana.PublicKey, amount, maxSolCost uint64) (solana.Instruction, error) {
+	curve, associatedCurve, associatedUser, err := tradeAccounts(mint, user)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(GlobalAddress),
+		solana.Meta(feeRecipient).WRITE(),
+		solana.Meta(mint),
+		solana.Meta(curve).WRITE(),
+		solana.Meta(associatedCurve).WRITE(),
+		solana.Meta(associatedUser).WRITE(),
+		solana.Meta(user).WRITE().SIGNER(),
+		solana.Meta(solana.SystemProgramID),
+		solana.Meta(solana.TokenProgramID),
+		solana.Meta(solana.SysVarRentPubkey),
+		solana.Meta(EventAuthority),
+		solana.Meta(ProgramID),
+	}
+
+	data := instructionData(buyDiscriminator, amount, maxSolCost)
+	return solana.NewInstruction(ProgramID, accounts, data), nil
+}
+
+// Sell sells amount raw tokens of mint, failing if they'd return less than minSolOutput lamports after the fee
+func Sell(mint, user, feeRecipient solana.PublicKey, amount, minSolOutput uint64) (solana.Instruction, error) {
+	curve, associatedCurve, associatedUser, err := tradeAccounts(mint, user)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(GlobalAddress),
+		solana.Meta(feeRecipient).WRITE(),
+		solana.Meta(mint),
+		solana.Meta(curve).WRITE(),
+		solana.Meta(associatedCurve).WRITE(),
+		solana.Meta(associatedUser).WRITE(),
+		solana.Meta(user).WRITE().SIGNER(),
+		solana.Meta(solana.SystemProgramID),
+		solana.Meta(solana.SPLAssociatedTokenAccountProgramID),
+		solana.Meta(solana.TokenProgramID),
+		solana.Meta(EventAuthority),
+		solana.Meta(ProgramID),
+	}
+
+	data := instructionData(sellDiscriminator, amount, minSolOutput)
+	return solana.NewInstruction(ProgramID, accounts, data), nil
+}
+
+// Withdraw moves a completed curve's liquidity out for migration, only the Global withdraw authority can sign it
+func Withdraw(mint, user solana.PublicKey) (solana.Instruction, error) {
+	curve, associatedCurve, associatedUser, err := tradeAccounts(mint, user)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(GlobalAddress),
+		solana.Meta(mint),
+		solana.Meta(curve).WRITE(),
+		solana.Meta(associatedCurve).WRITE(),
+		solana.Meta(associatedUser).WRITE(),
+		solana.Meta(user).WRITE().SIGNER(),
+		solana.Meta(solana.SystemProgramID),
+		solana.Meta(solana.TokenProgramID),
+		solana.Meta(solana.SysVarRentPubkey),
+		solana.Meta(EventAuthority),
+		solana.Meta(ProgramID),
+	}
+
+	data := instructionData(withdrawDiscriminator)
+	return solana.NewInstruction(ProgramID, accounts, data), nil
+}
+
+// SetParams rewrites the Global curve parameters, only the Global authority can sign it
+func SetParams(user solana.PublicKey, params Params) solana.Instruction {
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(GlobalAddress).WRITE(),
+		solana.Meta(user).WRITE().SIGNER(),
+		solana.Meta(solana.SystemProgramID),
+		solana.Meta(EventAuthority),
+		solana.Meta(ProgramID),
+	}
+
+	data := instructionData(setParamsDiscriminator,
+		params.FeeRecipient,
+		params.InitialVirtualTokenReserves,
+		params.InitialVirtualSolReserves,
+		params.InitialRealTokenReserves,
+		params.TokenTotalSupply,
+		params.FeeBasisPoints,
+	)
+	return solana.NewInstruction(ProgramID, accounts, data)
+}
+
+// tradeAccounts are the curve, the curve's token account and the user's token account of mint
+func tradeAccounts(mint, user solana.PublicKey) (curve, associatedCurve, associatedUser solana.PublicKey, err error) {
+	if curve, err = BondingCurveAddress(mint); err != nil {
+		return
+	}
+	if associatedCurve, _, err = solana.FindAssociatedTokenAddress(curve, mint); err != nil {
+		return
+	}
+	associatedUser, _, err = solana.FindAssociatedTokenAddress(user, mint)
+	return
+}
+
+// instructionData borsh encodes the discriminator followed by the arguments
+func instructionData(discriminator [8]byte, args ...interface{}) []byte {
+	var buf bytes.Buffer
+	buf.Write(discriminator[:])
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			binary.Write(&buf, binary.LittleEndian, uint32(len(v)))
+			buf.WriteString(v)
+		case solana.PublicKey:
+			buf.Write(v.Bytes())
+		default:
+			binary.Write(&buf, binary.LittleEndian, v)
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,83 @@
+// Package program builds pump.fun program instructions and decodes its accounts and events, following the program IDL
+package program
+
+import (
+	"crypto/sha256"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var (
+	ProgramID = solana.MustPublicKeyFromBase58("6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P")
+	// FeeRecipient is the fee recipient set in the Global account at the time of writing, FetchGlobal has the current one
+	FeeRecipient = solana.MustPublicKeyFromBase58("CebN5WGQ4jvEPvsVU4EoHEpgzq1VV7AbicfhtW4xC9iM")
+	// MetadataProgramID is metaplex token metadata, create makes the coin's metadata account with it
+	MetadataProgramID = solana.MustPublicKeyFromBase58("metaqbxxUerdq28cj1RbAWkYQm3ybzjb6a8bt518x1s")
+)
+
+// the program's fixed PDAs
+var (
+	// GlobalAddress is the program's config account, seeds ["global"]
+	GlobalAddress = mustPDA([]byte("global"))
+	// EventAuthority signs the program's event self invokes, seeds ["__event_authority"]
+	EventAuthority = mustPDA([]byte("__event_authority"))
+	// MintAuthority is the mint authority of every coin while it's on the curve, seeds ["mint-authority"]
+	MintAuthority = mustPDA([]byte("mint-authority"))
+)
+
+// anchor discriminators are the first 8 bytes of sha256("<namespace>:<name>")
+var (
+	createDiscriminator    = discriminator("global", "create")
+	buyDiscriminator       = discriminator("global", "buy")
+	sellDiscriminator      = discriminator("global", "sell")
+	withdrawDiscriminator  = discriminator("global", "withdraw")
+	setParamsDiscriminator = discriminator("global", "set_params")
+
+	GlobalAccountDiscriminator       = discriminator("account", "Global")
+	BondingCurveAccountDiscriminator = discriminator("account", "BondingCurve")
+
+	createEventDiscriminator    = discriminator("event", "CreateEvent")
+	tradeEventDiscriminator     = discriminator("event", "TradeEvent")
+	completeEventDiscriminator  = discriminator("event", "CompleteEvent")
+	setParamsEventDiscriminator = discriminator("event", "SetParamsEvent")
+)
+
+// eventIxTag starts the data of the self invoke anchor's emit_cpi logs events with
+var eventIxTag = []byte{0xe4, 0x45, 0xa5, 0x2e, 0x51, 0xcb, 0x9a, 0x1d}
+
+func discriminator(namespace, name string) [8]byte {
+	sum := sha256.Sum256([]byte(namespace + ":" + name))
+	var d [8]byte
+	copy(d[:], sum[:8])
+	return d
+}
+
+// BondingCurveAddress is the curve account of mint, seeds ["bonding-curve", mint]
+func BondingCurveAddress(mint solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress([][]byte{[]byte("bonding-curve"), mint.Bytes()}, ProgramID)
+	return address, err
+}
+
+// AssociatedBondingCurveAddress is the token account holding the curve's tokens, the curve's associated token account
+func AssociatedBondingCurveAddress(mint solana.PublicKey) (solana.PublicKey, error) {
+	curve, err := BondingCurveAddress(mint)
+	if err != nil {
+		return solana.PublicKey{}, err
+	}
+	address, _, err := solana.FindAssociatedTokenAddress(curve, mint)
+	return address, err
+}
+
+// MetadataAddress is the metaplex metadata account of mint
+func MetadataAddress(mint solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress([][]byte{[]byte("metadata"), MetadataProgramID.Bytes(), mint.Bytes()}, MetadataProgramID)
+	return address, err
+}
+
+func mustPDA(seeds ...[]byte) solana.PublicKey {
+	address, _, err := solana.FindProgramAddress(seeds, ProgramID)
+	if err != nil {
+		panic(err)
+	}
+	return address
+}
@@ -0,0 +1,166 @@
+package program
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// the values the wallet and the bonding curve decoder hard-coded before the program client derived them
+var (
+	oldGlobal         = solana.MustPublicKeyFromBase58("4wTV1YmiEkRvAtNtsSGPtUrqRYQMe5SKy2uB4Jjaxnjf")
+	oldEventAuthority = solana.MustPublicKeyFromBase58("Ce6TQqeHC9p8KetsN6JsjHK7UTZk7nasjjnr7XxXp9F1")
+)
+
+const (
+	oldBuyDiscriminator          uint64 = 16927863322537952870
+	oldSellDiscriminator         uint64 = 12502976635542562355
+	oldBondingCurveDiscriminator uint64 = 6966180631402821399
+)
+
+func TestDiscriminators(t *testing.T) {
+	tests := []struct {
+		name string
+		got  [8]byte
+		want uint64
+	}{
+		{"global:buy", buyDiscriminator, oldBuyDiscriminator},
+		{"global:sell", sellDiscriminator, oldSellDiscriminator},
+		{"account:BondingCurve", BondingCurveAccountDiscriminator, oldBondingCurveDiscriminator},
+	}
+
+	for _, tt := range tests {
+		if got := binary.LittleEndian.Uint64(tt.got[:]); got != tt.want {
+			t.Errorf("sha256(%q)[:8] = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAddresses(t *testing.T) {
+	if !GlobalAddress.Equals(oldGlobal) {
+		t.Errorf("GlobalAddress = %s, want %s", GlobalAddress, oldGlobal)
+	}
+	if !EventAuthority.Equals(oldEventAuthority) {
+		t.Errorf("EventAuthority = %s, want %s", EventAuthority, oldEventAuthority)
+	}
+
+	// the wrapped sol mint's curve accounts, computed independently of solana-go with the PDA rules that also give the two above
+	mint := solana.SolMint
+	wantCurve := solana.MustPublicKeyFromBase58("6PiyjiAPkp2KdZtqkyQYzVsD1Prv7t8v4TaYd8ip4YFd")
+	wantAssociated := solana.MustPublicKeyFromBase58("5ADoevzZMUvkzywQpnZVjWoqGvGAmghzs1jQmMpwj1GD")
+
+	curve, err := BondingCurveAddress(mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !curve.Equals(wantCurve) {
+		t.Errorf("BondingCurveAddress = %s, want %s", curve, wantCurve)
+	}
+	associated, err := AssociatedBondingCurveAddress(mint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !associated.Equals(wantAssociated) {
+		t.Errorf("AssociatedBondingCurveAddress = %s, want %s", associated, wantAssociated)
+	}
+}
+
+// Buy and Sell have to build exactly the instructions the wallet used to assemble by hand
+func TestTradeInstructions(t *testing.T) {
+	mint := solana.SolMint
+	user := solana.NewWallet().PublicKey()
+	curve, _ := BondingCurveAddress(mint)
+	associatedCurve, _ := AssociatedBondingCurveAddress(mint)
+	associatedUser, _, _ := solana.FindAssociatedTokenAddress(user, mint)
+
+	oldData := func(discriminator, amount, sol uint64) []byte {
+		var buf bytes.Buffer
+		binary.Write(&buf, binary.LittleEndian, discriminator)
+		binary.Write(&buf, binary.LittleEndian, amount)
+		binary.Write(&buf, binary.LittleEndian, sol)
+		return buf.Bytes()
+	}
+
+	buy, err := Buy(mint, user, FeeRecipient, 35_766, 1_000_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sell, err := Sell(mint, user, FeeRecipient, 35_766, 1_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		ix       solana.Instruction
+		data     []byte
+		accounts solana.AccountMetaSlice
+	}{
+		{
+			name: "buy",
+			ix:   buy,
+			data: oldData(oldBuyDiscriminator, 35_766, 1_000_000),
+			accounts: solana.AccountMetaSlice{
+				{PublicKey: oldGlobal},
+				{PublicKey: FeeRecipient, IsWritable: true},
+				{PublicKey: mint},
+				{PublicKey: curve, IsWritable: true},
+				{PublicKey: associatedCurve, IsWritable: true},
+				{PublicKey: associatedUser, IsWritable: true},
+				{PublicKey: user, IsSigner: true, IsWritable: true},
+				{PublicKey: solana.SystemProgramID},
+				{PublicKey: solana.TokenProgramID},
+				{PublicKey: solana.SysVarRentPubkey},
+				{PublicKey: oldEventAuthority},
+				{PublicKey: ProgramID},
+			},
+		},
+		{
+			name: "sell",
+			ix:   sell,
+			data: oldData(oldSellDiscriminator, 35_766, 1_000),
+			accounts: solana.AccountMetaSlice{
+				{PublicKey: oldGlobal},
+				{PublicKey: FeeRecipient, IsWritable: true},
+				{PublicKey: mint},
+				{PublicKey: curve, IsWritable: true},
+				{PublicKey: associatedCurve, IsWritable: true},
+				{PublicKey: associatedUser, IsWritable: true},
+				{PublicKey: user, IsSigner: true, IsWritable: true},
+				{PublicKey: solana.SystemProgramID},
+				{PublicKey: solana.SPLAssociatedTokenAccountProgramID},
+				{PublicKey: solana.TokenProgramID},
+				{PublicKey: oldEventAuthority},
+				{PublicKey: ProgramID},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.ix.ProgramID().Equals(ProgramID) {
+				t.Errorf("program %s, want %s", tt.ix.ProgramID(), ProgramID)
+			}
+			data, err := tt.ix.Data()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, tt.data) {
+				t.Errorf("data %x, want %x", data, tt.data)
+			}
+			accounts := tt.ix.Accounts()
+			if len(accounts) != len(tt.accounts) {
+				t.Fatalf("%d accounts, want %d", len(accounts), len(tt.accounts))
+			}
+			for i, want := range tt.accounts {
+				got := accounts[i]
+				if !got.PublicKey.Equals(want.PublicKey) || got.IsSigner != want.IsSigner || got.IsWritable != want.IsWritable {
+					t.Errorf("account %d is %s signer %v writable %v, want %s signer %v writable %v",
+						i, got.PublicKey, got.IsSigner, got.IsWritable, want.PublicKey, want.IsSigner, want.IsWritable)
+				}
+			}
+		})
+	}
+}
@@ -4,21 +4,49 @@ import (
 	"context"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 	"github.com/codingsandmore/pumpfun-portal/portal"
-	"github.com/codingsandmore/pumpfun-portal/portal/server"
 	"github.com/gagliardetto/solana-go/rpc"
-	"github.com/rs/zerolog"
+	"github.com/patrickmn/go-cache"
 )
 
 type Pumpfun struct {
 	Client       *rpc.Client
 	TrendingWord string
-	PumpPortal   *server.PortalServer
+
+	discoverPair  func(p *portal.NewPairResponse)
+	discoverTrade func(p *portal.NewTradeResponse)
+	tracked       *cache.Cache // mints we've subscribed to trades for
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+}
+
+// Start reads the pumpportal feed and polls the trending word until ctx is cancelled or Close is called
+func (pf *Pumpfun) Start(ctx context.Context) {
+	ctx, pf.cancel = context.WithCancel(ctx)
+
+	pf.wg.Add(2)
+	go func() {
+		defer pf.wg.Done()
+		pf.discover(ctx)
+	}()
+	go func() {
+		defer pf.wg.Done()
+		pf.UpdateTrendingWord(ctx)
+	}()
+}
+
+// Close stops the feed and waits for the pair handlers and trend lookup in flight
+func (pf *Pumpfun) Close() {
+	if pf.cancel != nil {
+		pf.cancel()
+	}
+	pf.wg.Wait()
 }
 
 // check how far based on marketcap in sol
@@ -26,9 +54,18 @@ func (pf *Pumpfun) IsMemeTrending(name string) bool {
 	return strings.Contains(name, pf.TrendingWord)
 }
 
-func (pf *Pumpfun) UpdateTrendingWord() {
-	for range time.NewTicker(1 * time.Minute).C {
-		trending := pf.FetchTrendingWord()
+func (pf *Pumpfun) UpdateTrendingWord(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		trending := pf.FetchTrendingWord(ctx)
 
 		if len(trending) == 0 {
 			continue
@@ -36,10 +73,9 @@ func (pf *Pumpfun) UpdateTrendingWord() {
 
 		pf.TrendingWord = trending
 	}
-
 }
 
-func (pf *Pumpfun) FetchTrendingWord() (trending string) {
+func (pf *Pumpfun) FetchTrendingWord(ctx context.Context) (trending string) {
 	options := append(chromedp.DefaultExecAllocatorOptions[:],
 		chromedp.Flag("blink-settings", "imagesEnabled=false"),        // block all images
 		chromedp.Flag("headless", true),                               // Run in headless mode (no UI)
@@ -55,7 +91,7 @@ func (pf *Pumpfun) FetchTrendingWord() (trending string) {
 		chromedp.NoFirstRun,
 	)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
 	defer cancel()
 
 	allocatorCtx, cancel := chromedp.NewExecAllocator(ctx, options...)
@@ -99,21 +135,17 @@ func (pf *Pumpfun) FetchTrendingWord() (trending string) {
 	return
 }
 
-func NewPumpFun(rpcClient *rpc.Client, discoverTrade func(p *portal.NewTradeResponse)) *Pumpfun {
-	pf := &Pumpfun{
-		Client: rpcClient,
+// NewPumpFun sets up discovery of pairs and trades from pumpportal, nothing is read until Start.
+// discoverPair can be nil.
+func NewPumpFun(rpcClient *rpc.Client, discoverPair func(p *portal.NewPairResponse), discoverTrade func(p *portal.NewTradeResponse)) *Pumpfun {
+	if discoverPair == nil {
+		discoverPair = func(p *portal.NewPairResponse) {}
 	}
 
-	/*if pumpPortal {
-
-	}*/
-
-	zerolog.SetGlobalLevel(zerolog.Disabled)
-	pf.PumpPortal = server.NewPortalServer()
-	discoverPair := func(p *portal.NewPairResponse) {}
-
-	go pf.PumpPortal.Discover(discoverPair, discoverTrade)
-	go pf.UpdateTrendingWord()
-
-	return pf
+	return &Pumpfun{
+		Client:        rpcClient,
+		discoverPair:  discoverPair,
+		discoverTrade: discoverTrade,
+		tracked:       cache.New(30*time.Minute, 5*time.Minute),
+	}
 }
@@ -0,0 +1,58 @@
+package strategy
+
+import (
+	"time"
+
+	"github.com/codingsandmore/pumpfun-portal/portal"
+	"github.com/gagliardetto/solana-go"
+	"trader.fun/position"
+)
+
+// Exits sells positions on the take-profit, stop-loss, trailing stop and max hold rules
+type Exits struct {
+	Rules position.Rules
+	peaks map[solana.PublicKey]float64
+}
+
+func (e *Exits) Name() string {
+	return "exits"
+}
+
+func (e *Exits) OnNewPair(p *portal.NewPairResponse) []Intent {
+	return nil
+}
+
+func (e *Exits) OnTrade(p *portal.NewTradeResponse) []Intent {
+	return nil
+}
+
+func (e *Exits) OnTick(now time.Time, positions []Position) (intents []Intent) {
+	held := make(map[solana.PublicKey]bool, len(positions))
+	for _, pos := range positions {
+		held[pos.Coin.MintAddr] = true
+		if pos.Price == 0 {
+			continue
+		}
+
+		peak := max(e.peaks[pos.Coin.MintAddr], pos.Entry, pos.Price)
+		e.peaks[pos.Coin.MintAddr] = peak
+
+		if exit := e.Rules.Check(pos.Entry, peak, pos.Price, pos.Opened, now); exit != position.Hold {
+			intents = append(intents, Intent{Side: Sell, Coin: pos.Coin, Percentage: 100, Reason: string(exit)})
+		}
+	}
+
+	for mint := range e.peaks {
+		if !held[mint] {
+			delete(e.peaks, mint)
+		}
+	}
+	return
+}
+
+func NewExits(rules position.Rules) *Exits {
+	return &Exits{
+		Rules: rules,
+		peaks: make(map[solana.PublicKey]float64),
+	}
+}
@@ -0,0 +1,189 @@
+package strategy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/codingsandmore/pumpfun-portal/portal"
+	"github.com/gagliardetto/solana-go"
+	"trader.fun/pumpfun"
+	"trader.fun/ta"
+)
+
+// Fibonacci buys coins over a market cap floor when they pull back into a retracement of their last swing up.
+// Swings are the high and low of the last Lookback bars of Interval built from the trades, an entry also
+// needs the RSI at or under MaxRSI. The stop goes StopBuffer percent under the StopLevel retracement and
+// the target is the Target extension of the swing.
+type Fibonacci struct {
+	MinMarketCap float64 // sol
+	Interval     time.Duration
+	Lookback     int       // bars
+	Levels       []float64 // retracements to buy at, e.g. 0.5 and 0.618
+	MinSwing     float64   // percent the swing's high has to be over its low
+	RSIPeriod    int
+	MaxRSI       float64
+	StopLevel    float64 // retracement the stop goes under, 0.786 or 1
+	StopBuffer   float64 // percent
+	Target       float64 // extension to take profit at, e.g. 1.272 or 1.618
+	MaxHold      time.Duration
+
+	now   time.Time
+	mints map[string]*fibMint
+	plans map[solana.PublicKey]fibPlan
+}
+
+type fibMint struct {
+	bars   *pumpfun.Series
+	fed    time.Time // start of the last closed bar fed to the indicators
+	swing  *ta.Fibonacci
+	rsi    *ta.RSI
+	traded time.Time
+}
+
+type fibPlan struct {
+	stop, target float64
+	made         time.Time
+}
+
+func (f *Fibonacci) Name() string {
+	return "fibonacci"
+}
+
+func (f *Fibonacci) SetTime(now time.Time) {
+	f.now = now
+}
+
+func (f *Fibonacci) clock() time.Time {
+	if f.now.IsZero() {
+		return time.Now()
+	}
+	return f.now
+}
+
+func (f *Fibonacci) OnNewPair(p *portal.NewPairResponse) []Intent {
+	return nil
+}
+
+func (f *Fibonacci) OnTrade(p *portal.NewTradeResponse) []Intent {
+	if p.VTokensInBondingCurve <= 0 {
+		return nil
+	}
+	curve := pumpfun.NewBondingCurveFromReserves(p.VTokensInBondingCurve, p.VSolInBondingCurve)
+	price, now := curve.SpotPrice(), f.clock()
+
+	m, ok := f.mints[p.Mint]
+	if !ok {
+		m = &fibMint{
+			bars:  &pumpfun.Series{Interval: f.Interval, Keep: f.Lookback + 1},
+			swing: ta.NewFibonacci(f.Lookback),
+			rsi:   ta.NewRSI(f.RSIPeriod),
+		}
+		f.mints[p.Mint] = m
+	}
+	m.traded = now
+	m.bars.Add(price, 0, now)
+
+	// the swing and rsi only see closed bars, the forming one would repaint them
+	bars := m.bars.Last(0)
+	for _, bar := range bars[:len(bars)-1] {
+		if !bar.Time.After(m.fed) {
+			continue
+		}
+		m.swing.Update(bar.Bar())
+		m.rsi.Update(bar.Close)
+		m.fed = bar.Time
+	}
+
+	if curve.Complete || curve.MarketCapSol() < f.MinMarketCap || !m.swing.Ready() || !m.rsi.Ready() {
+		return nil
+	}
+	swing := m.swing.Value()
+	if !swing.Up || swing.Low <= 0 || swing.Range()/swing.Low*100 < f.MinSwing {
+		return nil
+	}
+	if m.rsi.Value() > f.MaxRSI {
+		return nil
+	}
+
+	stop := swing.Retracement(f.StopLevel) * (1 - f.StopBuffer/100)
+	if price <= stop {
+		return nil
+	}
+	// the deepest level price has pulled back to, it has to reach at least one
+	level := 0.0
+	for _, l := range f.Levels {
+		if price <= swing.Retracement(l) && l > level {
+			level = l
+		}
+	}
+	if level == 0 {
+		return nil
+	}
+
+	coin, ok := coinFromTrade(p)
+	if !ok {
+		return nil
+	}
+	if _, holding := f.plans[coin.MintAddr]; holding {
+		return nil
+	}
+	f.plans[coin.MintAddr] = fibPlan{stop: stop, target: swing.Extension(f.Target), made: now}
+	return []Intent{{Side: Buy, Coin: coin, Reason: fmt.Sprintf("fib %.1f%%", level*100)}}
+}
+
+func (f *Fibonacci) OnTick(now time.Time, positions []Position) (intents []Intent) {
+	held := make(map[solana.PublicKey]bool, len(positions))
+	for _, pos := range positions {
+		mint := pos.Coin.MintAddr
+		held[mint] = true
+
+		plan, ok := f.plans[mint]
+		if !ok || pos.Price == 0 {
+			continue
+		}
+		switch {
+		case pos.Price <= plan.stop:
+			intents = append(intents, Intent{Side: Sell, Coin: pos.Coin, Percentage: 100, Reason: "fib stop"})
+		case pos.Price >= plan.target:
+			intents = append(intents, Intent{Side: Sell, Coin: pos.Coin, Percentage: 100, Reason: "fib target"})
+		case f.MaxHold > 0 && now.Sub(pos.Opened) >= f.MaxHold:
+			intents = append(intents, Intent{Side: Sell, Coin: pos.Coin, Percentage: 100, Reason: "max hold time"})
+		}
+	}
+
+	// a plan is kept from the buy intent until its position is gone, a buy that never filled is forgotten after a bar
+	for mint, plan := range f.plans {
+		if !held[mint] && now.Sub(plan.made) > f.Interval {
+			delete(f.plans, mint)
+		}
+	}
+
+	// a mint that stopped trading for a whole lookback starts over
+	idle := f.Interval * time.Duration(f.Lookback)
+	for mint, m := range f.mints {
+		if now.Sub(m.traded) > idle {
+			delete(f.mints, mint)
+		}
+	}
+	return
+}
+
+// NewFibonacci buys 50% and 61.8% pullbacks of 15 second swings over the last 5 minutes with the RSI under 40,
+// stopping out 2% under the 78.6% retracement and taking profit at the 1.272 extension
+func NewFibonacci(minMarketCap float64) *Fibonacci {
+	return &Fibonacci{
+		MinMarketCap: minMarketCap,
+		Interval:     15 * time.Second,
+		Lookback:     20,
+		Levels:       []float64{0.5, 0.618},
+		MinSwing:     20,
+		RSIPeriod:    14,
+		MaxRSI:       40,
+		StopLevel:    0.786,
+		StopBuffer:   2,
+		Target:       1.272,
+		MaxHold:      30 * time.Minute,
+		mints:        make(map[string]*fibMint),
+		plans:        make(map[solana.PublicKey]fibPlan),
+	}
+}
@@ -0,0 +1,64 @@
+package strategy
+
+import (
+	"time"
+
+	"github.com/codingsandmore/pumpfun-portal/portal"
+	"github.com/patrickmn/go-cache"
+	"trader.fun/indicator"
+)
+
+// Indicator buys what the onnx model picks and sells after Horizon, the delay the model was trained on.
+// The model compiles its inputs from the pump.fun web apis, so it can't run offline.
+type Indicator struct {
+	Horizon time.Duration
+	seen    *cache.Cache
+}
+
+func (s *Indicator) Name() string {
+	return "indicator"
+}
+
+func (s *Indicator) OnNewPair(p *portal.NewPairResponse) []Intent {
+	return nil
+}
+
+func (s *Indicator) OnTrade(p *portal.NewTradeResponse) []Intent {
+	if p.MarketCapSol == 0 {
+		return nil
+	}
+
+	coin, ok := coinFromTrade(p)
+	if !ok {
+		return nil
+	}
+
+	// only score a mint once a minute, compiling the inputs is a dozen http calls
+	if _, found := s.seen.Get(p.Mint); found {
+		return nil
+	}
+	s.seen.Set(p.Mint, true, cache.DefaultExpiration)
+
+	return []Intent{{Side: Buy, Coin: coin, Reason: "model", Confirm: func() bool {
+		return indicator.ShouldBuy(&coin)
+	}}}
+}
+
+func (s *Indicator) OnTick(now time.Time, positions []Position) (intents []Intent) {
+	if s.Horizon == 0 {
+		return nil
+	}
+	for _, pos := range positions {
+		if now.Sub(pos.Opened) >= s.Horizon {
+			intents = append(intents, Intent{Side: Sell, Coin: pos.Coin, Percentage: 100, Reason: "horizon"})
+		}
+	}
+	return
+}
+
+func NewIndicator(horizon time.Duration) *Indicator {
+	return &Indicator{
+		Horizon: horizon,
+		seen:    cache.New(1*time.Minute, 1*time.Minute),
+	}
+}
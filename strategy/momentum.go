@@ -0,0 +1,59 @@
+package strategy
+
+import (
+	"time"
+
+	"github.com/codingsandmore/pumpfun-portal/portal"
+	"trader.fun/pumpfun"
+)
+
+// Momentum enters a mint after Buys buys in a row while its market cap is inside the range
+type Momentum struct {
+	Buys         int
+	MinMarketCap float64 // sol
+	MaxMarketCap float64 // sol, zero for no limit
+	streaks      map[string]int
+}
+
+func (m *Momentum) Name() string {
+	return "momentum"
+}
+
+func (m *Momentum) OnNewPair(p *portal.NewPairResponse) []Intent {
+	return nil
+}
+
+func (m *Momentum) OnTrade(p *portal.NewTradeResponse) []Intent {
+	if p.TxType != "buy" {
+		delete(m.streaks, p.Mint)
+		return nil
+	}
+	m.streaks[p.Mint]++
+
+	marketCap := pumpfun.NewBondingCurveFromReserves(p.VTokensInBondingCurve, p.VSolInBondingCurve).MarketCapSol()
+	if marketCap < m.MinMarketCap || (m.MaxMarketCap > 0 && marketCap > m.MaxMarketCap) {
+		return nil
+	}
+	if m.streaks[p.Mint] < m.Buys {
+		return nil
+	}
+
+	coin, ok := coinFromTrade(p)
+	if !ok {
+		return nil
+	}
+	return []Intent{{Side: Buy, Coin: coin, Reason: "buy streak"}}
+}
+
+func (m *Momentum) OnTick(now time.Time, positions []Position) []Intent {
+	return nil
+}
+
+func NewMomentum(buys int, minMarketCap, maxMarketCap float64) *Momentum {
+	return &Momentum{
+		Buys:         buys,
+		MinMarketCap: minMarketCap,
+		MaxMarketCap: maxMarketCap,
+		streaks:      make(map[string]int),
+	}
+}
@@ -0,0 +1,124 @@
+package strategy
+
+import (
+	"time"
+
+	"github.com/codingsandmore/pumpfun-portal/portal"
+	"github.com/gagliardetto/solana-go"
+	"trader.fun/pumpfun"
+)
+
+type Side string
+
+const (
+	Buy  Side = "buy"
+	Sell Side = "sell"
+)
+
+// Intent is what a strategy wants done, the trader decides whether and how to do it
+type Intent struct {
+	Side       Side
+	Coin       pumpfun.Coin
+	Sol        float64 // sol to spend on a buy, zero for the trader's default size
+	Percentage float64 // percent of the position to sell
+	Reason     string
+	// Confirm, when set, is slow work like a network call that decides whether the intent goes ahead.
+	// It runs outside the strategy so it mustn't touch the strategy's state.
+	Confirm func() bool
+}
+
+// Position is what a strategy sees of an open position
+type Position struct {
+	Coin   pumpfun.Coin
+	Amount float64 // whole tokens
+	Entry  float64 // average sol paid per token
+	Price  float64 // latest sol per token, zero when unknown
+	Opened time.Time
+}
+
+// Strategy is driven the same way by the live trader, the paper trader and the backtester.
+// now is wall time live and event time in a backtest.
+type Strategy interface {
+	Name() string
+	OnNewPair(p *portal.NewPairResponse) []Intent
+	OnTrade(p *portal.NewTradeResponse) []Intent
+	OnTick(now time.Time, positions []Position) []Intent
+}
+
+// PriceListener is implemented by strategies that react to bonding curve updates as the price feed receives them
+type PriceListener interface {
+	OnPrice(update pumpfun.CurveUpdate) []Intent
+}
+
+// Clock is implemented by strategies that keep time of their own, e.g. to build bars from trades.
+// The backtester sets it to each event's time before passing the event on, live they read the wall clock.
+type Clock interface {
+	SetTime(now time.Time)
+}
+
+type combined struct {
+	name       string
+	strategies []Strategy
+}
+
+// Combine runs every strategy and concatenates their intents, e.g. an entry signal with exit rules
+func Combine(name string, strategies ...Strategy) Strategy {
+	return &combined{name: name, strategies: strategies}
+}
+
+func (c *combined) Name() string {
+	return c.name
+}
+
+func (c *combined) OnNewPair(p *portal.NewPairResponse) (intents []Intent) {
+	for _, s := range c.strategies {
+		intents = append(intents, s.OnNewPair(p)...)
+	}
+	return
+}
+
+func (c *combined) OnTrade(p *portal.NewTradeResponse) (intents []Intent) {
+	for _, s := range c.strategies {
+		intents = append(intents, s.OnTrade(p)...)
+	}
+	return
+}
+
+func (c *combined) OnTick(now time.Time, positions []Position) (intents []Intent) {
+	for _, s := range c.strategies {
+		intents = append(intents, s.OnTick(now, positions)...)
+	}
+	return
+}
+
+func (c *combined) OnPrice(update pumpfun.CurveUpdate) (intents []Intent) {
+	for _, s := range c.strategies {
+		if listener, ok := s.(PriceListener); ok {
+			intents = append(intents, listener.OnPrice(update)...)
+		}
+	}
+	return
+}
+
+func (c *combined) SetTime(now time.Time) {
+	for _, s := range c.strategies {
+		if clock, ok := s.(Clock); ok {
+			clock.SetTime(now)
+		}
+	}
+}
+
+// coinFromTrade builds the coin a trade event is for, ok is false when the event has no valid mint.
+// The curve accounts are derived from the mint, so events missing the bonding curve key still trade.
+func coinFromTrade(p *portal.NewTradeResponse) (coin pumpfun.Coin, ok bool) {
+	mint, err := solana.PublicKeyFromBase58(p.Mint)
+	if err != nil {
+		return coin, false
+	}
+	derived, err := pumpfun.NewCoin(mint)
+	if err != nil {
+		return coin, false
+	}
+	derived.MarketCap = p.MarketCapSol
+	return *derived, true
+}
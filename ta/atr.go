@@ -0,0 +1,41 @@
+package ta
+
+import "math"
+
+// ATR is Wilder's average true range, the mean of the first Period true ranges and smoothed by 1/Period after
+type ATR struct {
+	Period    int
+	prevClose float64
+	bars      int
+	value     float64
+}
+
+func NewATR(period int) *ATR {
+	return &ATR{Period: max(period, 1)}
+}
+
+func (a *ATR) Update(bar Bar) float64 {
+	tr := bar.High - bar.Low
+	if a.bars > 0 {
+		tr = max(tr, math.Abs(bar.High-a.prevClose), math.Abs(bar.Low-a.prevClose))
+	}
+	a.prevClose = bar.Close
+
+	a.bars++
+	p := float64(a.Period)
+	if a.bars <= a.Period {
+		// running mean until the period fills
+		a.value += (tr - a.value) / float64(a.bars)
+	} else {
+		a.value = (a.value*(p-1) + tr) / p
+	}
+	return a.value
+}
+
+func (a *ATR) Value() float64 {
+	return a.value
+}
+
+func (a *ATR) Ready() bool {
+	return a.bars >= a.Period
+}
@@ -0,0 +1,22 @@
+package ta
+
+import "testing"
+
+func TestATR(t *testing.T) {
+	// true ranges 2, 2, 2, 2.5 (the low under the last close), 4.5 (a gap over the last close)
+	bars := []Bar{
+		{High: 10, Low: 8, Close: 9},
+		{High: 11, Low: 9, Close: 10.5},
+		{High: 12, Low: 10, Close: 11},
+		{High: 11.5, Low: 9, Close: 9.5},
+		{High: 14, Low: 12, Close: 13},
+	}
+	want := []float64{2, 2, 2, (2*2 + 2.5) / 3.0, ((2*2+2.5)/3.0*2 + 4.5) / 3}
+
+	atr := NewATR(3)
+	got := make([]float64, len(bars))
+	for i, bar := range bars {
+		got[i] = atr.Update(bar)
+	}
+	checkSeries(t, "ATR(3)", got, want, 1e-9)
+}
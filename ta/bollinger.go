@@ -0,0 +1,60 @@
+package ta
+
+import "math"
+
+// Bollinger bands sit K population standard deviations of the last Period closes either side of their SMA
+type Bollinger struct {
+	Period int
+	K      float64
+	w      *window
+}
+
+type Bands struct {
+	Upper, Middle, Lower float64
+}
+
+// Width is the distance between the bands relative to the middle
+func (b Bands) Width() float64 {
+	if b.Middle == 0 {
+		return 0
+	}
+	return (b.Upper - b.Lower) / b.Middle
+}
+
+// PercentB is where price sits between the bands, 0 on the lower and 1 on the upper
+func (b Bands) PercentB(price float64) float64 {
+	if b.Upper == b.Lower {
+		return 0.5
+	}
+	return (price - b.Lower) / (b.Upper - b.Lower)
+}
+
+func NewBollinger(period int, k float64) *Bollinger {
+	return &Bollinger{Period: period, K: k, w: newWindow(period)}
+}
+
+func (b *Bollinger) Update(close float64) Bands {
+	b.w.push(close)
+	return b.Value()
+}
+
+func (b *Bollinger) Value() Bands {
+	mean := b.w.mean()
+	sd := b.StdDev()
+	return Bands{Upper: mean + b.K*sd, Middle: mean, Lower: mean - b.K*sd}
+}
+
+// StdDev is the population standard deviation of the closes in the period
+func (b *Bollinger) StdDev() float64 {
+	c := float64(b.w.count())
+	if c == 0 {
+		return 0
+	}
+	mean := b.w.sum / c
+	// rounding can leave the variance of equal closes a hair under zero
+	return math.Sqrt(max(b.w.sumSq/c-mean*mean, 0))
+}
+
+func (b *Bollinger) Ready() bool {
+	return b.w.full
+}
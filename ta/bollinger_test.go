@@ -0,0 +1,30 @@
+package ta
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBollinger(t *testing.T) {
+	// population standard deviation: [2 4 6] is sqrt(8/3), [4 6 9] is sqrt(38/9)
+	tests := []struct {
+		name   string
+		closes []float64
+		want   Bands
+	}{
+		{"first window", []float64{2, 4, 6}, Bands{Upper: 4 + 2*math.Sqrt(8.0/3), Middle: 4, Lower: 4 - 2*math.Sqrt(8.0/3)}},
+		{"rolled", []float64{2, 4, 6, 9}, Bands{Upper: 19.0/3 + 2*math.Sqrt(38.0/9), Middle: 19.0 / 3, Lower: 19.0/3 - 2*math.Sqrt(38.0/9)}},
+		{"flat", []float64{5, 5, 5, 5}, Bands{Upper: 5, Middle: 5, Lower: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBollinger(3, 2)
+			var got Bands
+			for _, c := range tt.closes {
+				got = b.Update(c)
+			}
+			checkSeries(t, "bands", []float64{got.Upper, got.Middle, got.Lower}, []float64{tt.want.Upper, tt.want.Middle, tt.want.Lower}, 1e-9)
+		})
+	}
+}
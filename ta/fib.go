@@ -0,0 +1,93 @@
+package ta
+
+// Retracement and extension ratios of a swing
+var (
+	Retracements = []float64{0.236, 0.382, 0.5, 0.618, 0.786, 1}
+	Extensions   = []float64{1.272, 1.618, 2, 2.618}
+)
+
+// Swing is the highest high and lowest low of a lookback and which came last
+type Swing struct {
+	High, Low float64
+	Up        bool // the low came before the high, so retracements are measured down from the high
+}
+
+func (s Swing) Range() float64 {
+	return s.High - s.Low
+}
+
+// Retracement is the price ratio of the swing back from its end, 0 is the end and 1 its start
+func (s Swing) Retracement(ratio float64) float64 {
+	if s.Up {
+		return s.High - s.Range()*ratio
+	}
+	return s.Low + s.Range()*ratio
+}
+
+// Extension is the price ratio of the swing past its start in the swing's direction, 1 is the end
+func (s Swing) Extension(ratio float64) float64 {
+	if s.Up {
+		return s.Low + s.Range()*ratio
+	}
+	return s.High - s.Range()*ratio
+}
+
+// Levels is the retracement price of each ratio
+func (s Swing) Levels(ratios []float64) []float64 {
+	levels := make([]float64, len(ratios))
+	for i, ratio := range ratios {
+		levels[i] = s.Retracement(ratio)
+	}
+	return levels
+}
+
+// Fibonacci follows the swing of the last Period bars, keeping the running high and low in
+// monotonic queues so each bar costs constant time on average
+type Fibonacci struct {
+	Period int
+	highs  []point // falling highs, the front is the period's highest
+	lows   []point // rising lows, the front is the period's lowest
+	bars   int
+}
+
+type point struct {
+	bar   int
+	price float64
+}
+
+func NewFibonacci(period int) *Fibonacci {
+	return &Fibonacci{Period: max(period, 1)}
+}
+
+func (f *Fibonacci) Update(bar Bar) Swing {
+	for len(f.highs) > 0 && f.highs[len(f.highs)-1].price <= bar.High {
+		f.highs = f.highs[:len(f.highs)-1]
+	}
+	f.highs = append(f.highs, point{f.bars, bar.High})
+	for len(f.lows) > 0 && f.lows[len(f.lows)-1].price >= bar.Low {
+		f.lows = f.lows[:len(f.lows)-1]
+	}
+	f.lows = append(f.lows, point{f.bars, bar.Low})
+
+	f.bars++
+	oldest := f.bars - f.Period
+	if f.highs[0].bar < oldest {
+		f.highs = f.highs[1:]
+	}
+	if f.lows[0].bar < oldest {
+		f.lows = f.lows[1:]
+	}
+	return f.Value()
+}
+
+func (f *Fibonacci) Value() Swing {
+	if f.bars == 0 {
+		return Swing{}
+	}
+	high, low := f.highs[0], f.lows[0]
+	return Swing{High: high.price, Low: low.price, Up: high.bar >= low.bar}
+}
+
+func (f *Fibonacci) Ready() bool {
+	return f.bars >= f.Period
+}
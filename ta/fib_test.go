@@ -0,0 +1,58 @@
+package ta
+
+import "testing"
+
+func TestFibonacci(t *testing.T) {
+	tests := []struct {
+		name        string
+		bars        []Bar
+		period      int
+		want        Swing
+		retracement map[float64]float64
+		extension   map[float64]float64
+	}{
+		{
+			name:        "upswing",
+			bars:        []Bar{{High: 12, Low: 10}, {High: 16, Low: 13}, {High: 20, Low: 17}},
+			period:      3,
+			want:        Swing{High: 20, Low: 10, Up: true},
+			retracement: map[float64]float64{0.382: 16.18, 0.5: 15, 0.618: 13.82, 1: 10},
+			extension:   map[float64]float64{1.272: 22.72, 1.618: 26.18},
+		},
+		{
+			name:        "downswing",
+			bars:        []Bar{{High: 20, Low: 17}, {High: 16, Low: 13}, {High: 12, Low: 10}},
+			period:      3,
+			want:        Swing{High: 20, Low: 10, Up: false},
+			retracement: map[float64]float64{0.382: 13.82, 0.618: 16.18},
+			extension:   map[float64]float64{1.272: 7.28},
+		},
+		{
+			// the 30 high falls out of the lookback
+			name:        "rolled",
+			bars:        []Bar{{High: 30, Low: 25}, {High: 12, Low: 10}, {High: 16, Low: 13}, {High: 20, Low: 17}},
+			period:      3,
+			want:        Swing{High: 20, Low: 10, Up: true},
+			retracement: map[float64]float64{0.5: 15},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFibonacci(tt.period)
+			var got Swing
+			for _, bar := range tt.bars {
+				got = f.Update(bar)
+			}
+			if got != tt.want {
+				t.Fatalf("swing = %+v, want %+v", got, tt.want)
+			}
+			for ratio, want := range tt.retracement {
+				checkSeries(t, "retracement", []float64{got.Retracement(ratio)}, []float64{want}, 1e-9)
+			}
+			for ratio, want := range tt.extension {
+				checkSeries(t, "extension", []float64{got.Extension(ratio)}, []float64{want}, 1e-9)
+			}
+		})
+	}
+}
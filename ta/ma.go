@@ -0,0 +1,87 @@
+package ta
+
+// SMA is the simple moving average of the last Period closes, the average of every close so far until then
+type SMA struct {
+	Period int
+	w      *window
+}
+
+func NewSMA(period int) *SMA {
+	return &SMA{Period: period, w: newWindow(period)}
+}
+
+func (s *SMA) Update(close float64) float64 {
+	s.w.push(close)
+	return s.Value()
+}
+
+func (s *SMA) Value() float64 {
+	return s.w.mean()
+}
+
+func (s *SMA) Ready() bool {
+	return s.w.full
+}
+
+// EMA is the exponential moving average with a smoothing of 2/(Period+1), seeded with the SMA of the first Period closes
+type EMA struct {
+	Period int
+	alpha  float64
+	seed   *SMA
+	value  float64
+}
+
+func NewEMA(period int) *EMA {
+	return &EMA{Period: period, alpha: 2 / (float64(max(period, 1)) + 1), seed: NewSMA(period)}
+}
+
+func (e *EMA) Update(close float64) float64 {
+	if !e.seed.Ready() {
+		e.value = e.seed.Update(close)
+		return e.value
+	}
+	e.value += e.alpha * (close - e.value)
+	return e.value
+}
+
+func (e *EMA) Value() float64 {
+	return e.value
+}
+
+func (e *EMA) Ready() bool {
+	return e.seed.Ready()
+}
+
+// WMA is the linearly weighted moving average of the last Period closes, the latest weighing Period and the oldest 1
+type WMA struct {
+	Period    int
+	w         *window
+	numerator float64
+}
+
+func NewWMA(period int) *WMA {
+	return &WMA{Period: period, w: newWindow(period)}
+}
+
+func (m *WMA) Update(close float64) float64 {
+	sum := m.w.sum
+	if _, full := m.w.push(close); full {
+		// every close in the window loses one weight, the oldest drops out at zero
+		m.numerator += float64(m.w.n)*close - sum
+	} else {
+		m.numerator += float64(m.w.count()) * close
+	}
+	return m.Value()
+}
+
+func (m *WMA) Value() float64 {
+	c := float64(m.w.count())
+	if c == 0 {
+		return 0
+	}
+	return m.numerator / (c * (c + 1) / 2)
+}
+
+func (m *WMA) Ready() bool {
+	return m.w.full
+}
@@ -0,0 +1,65 @@
+package ta
+
+import "testing"
+
+// the 10-day moving average example from StockCharts' "Moving Averages - Simple and Exponential"
+var stockChartsCloses = []float64{
+	22.27, 22.19, 22.08, 22.17, 22.18, 22.13, 22.23, 22.43, 22.24, 22.29,
+	22.15, 22.39, 22.38, 22.61, 23.36, 24.05, 23.75, 23.83, 23.95, 23.63,
+	23.82, 23.87, 23.65, 23.19, 23.10, 23.33, 22.68, 23.10, 22.40, 22.17,
+}
+
+func TestMovingAverages(t *testing.T) {
+	tests := []struct {
+		name      string
+		indicator Indicator
+		closes    []float64
+		want      []float64
+		tolerance float64
+	}{
+		{
+			name:      "SMA(10)",
+			indicator: NewSMA(10),
+			closes:    stockChartsCloses,
+			want: warmup(9,
+				22.22, 22.21, 22.23, 22.26, 22.31, 22.42, 22.61, 22.77, 22.91, 23.08,
+				23.21, 23.38, 23.53, 23.65, 23.71, 23.69, 23.61, 23.51, 23.43, 23.28, 23.13),
+			tolerance: 0.01,
+		},
+		{
+			name:      "EMA(10)",
+			indicator: NewEMA(10),
+			closes:    stockChartsCloses,
+			want: warmup(9,
+				22.22, 22.21, 22.24, 22.27, 22.33, 22.52, 22.80, 22.97, 23.13, 23.28,
+				23.34, 23.43, 23.51, 23.54, 23.47, 23.40, 23.39, 23.26, 23.23, 23.08, 22.92),
+			tolerance: 0.01,
+		},
+		{
+			// (1*c[i-2] + 2*c[i-1] + 3*c[i]) / 6
+			name:      "WMA(3)",
+			indicator: NewWMA(3),
+			closes:    []float64{10, 12, 11, 14, 13},
+			want:      warmup(2, 67.0/6, 76.0/6, 78.0/6),
+			tolerance: 1e-9,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkSeries(t, tt.name, Series(tt.indicator, tt.closes), tt.want, tt.tolerance)
+			if !tt.indicator.Ready() {
+				t.Errorf("%s not ready after %d closes", tt.name, len(tt.closes))
+			}
+		})
+	}
+}
+
+func TestMovingAveragesNotReadyUntilPeriod(t *testing.T) {
+	for _, ind := range []Indicator{NewSMA(3), NewEMA(3), NewWMA(3), NewRSI(3)} {
+		Series(ind, []float64{1, 2})
+		if ind.Ready() {
+			t.Errorf("%T ready after 2 closes with a period of 3", ind)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package ta
+
+// MACD is the fast EMA less the slow EMA, with an EMA of that over Signal closes as its signal line.
+// The signal line starts once the slow EMA is ready.
+type MACD struct {
+	fast, slow, signal *EMA
+	value              MACDValue
+}
+
+type MACDValue struct {
+	MACD, Signal, Histogram float64
+}
+
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{fast: NewEMA(fast), slow: NewEMA(slow), signal: NewEMA(signal)}
+}
+
+func (m *MACD) Update(close float64) MACDValue {
+	macd := m.fast.Update(close) - m.slow.Update(close)
+	m.value = MACDValue{MACD: macd}
+	if m.slow.Ready() {
+		m.value.Signal = m.signal.Update(macd)
+		m.value.Histogram = macd - m.value.Signal
+	}
+	return m.value
+}
+
+func (m *MACD) Value() MACDValue {
+	return m.value
+}
+
+func (m *MACD) Ready() bool {
+	return m.slow.Ready() && m.signal.Ready()
+}
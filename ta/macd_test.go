@@ -0,0 +1,35 @@
+package ta
+
+import "testing"
+
+func TestMACD(t *testing.T) {
+	// worked by hand: EMA(2) smooths by 2/3 and EMA(3) by 1/2, each seeded with its SMA,
+	// and the signal EMA(2) starts on the bar the slow EMA is ready
+	closes := []float64{1, 3, 2, 5, 4, 6}
+	tests := []struct {
+		name string
+		get  func(MACDValue) float64
+		want []float64
+	}{
+		{"macd", func(v MACDValue) float64 { return v.MACD }, []float64{0, 0, 0, 0.5, 0.25, 0.4583333}},
+		{"signal", func(v MACDValue) float64 { return v.Signal }, warmup(3, 0.25, 0.25, 0.3888889)},
+		{"histogram", func(v MACDValue) float64 { return v.Histogram }, warmup(3, 0.25, 0, 0.0694444)},
+	}
+
+	m := NewMACD(2, 3, 2)
+	values := make([]MACDValue, len(closes))
+	for i, c := range closes {
+		values[i] = m.Update(c)
+	}
+	if !m.Ready() {
+		t.Fatal("MACD not ready")
+	}
+
+	for _, tt := range tests {
+		got := make([]float64, len(values))
+		for i, v := range values {
+			got[i] = tt.get(v)
+		}
+		checkSeries(t, tt.name, got, tt.want, 1e-6)
+	}
+}
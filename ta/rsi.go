@@ -0,0 +1,59 @@
+package ta
+
+// RSI is Wilder's relative strength index from 0 to 100. The first Period changes are averaged,
+// after that the average gain and loss are smoothed by 1/Period.
+type RSI struct {
+	Period           int
+	prev             float64
+	started          bool
+	changes          int
+	avgGain, avgLoss float64
+	value            float64
+}
+
+func NewRSI(period int) *RSI {
+	return &RSI{Period: max(period, 1), value: 50}
+}
+
+func (r *RSI) Update(close float64) float64 {
+	if !r.started {
+		r.prev, r.started = close, true
+		return r.value
+	}
+
+	change := close - r.prev
+	r.prev = close
+	gain, loss := max(change, 0), max(-change, 0)
+
+	r.changes++
+	p := float64(r.Period)
+	if r.changes <= r.Period {
+		r.avgGain += gain / p
+		r.avgLoss += loss / p
+		if r.changes < r.Period {
+			return r.value
+		}
+	} else {
+		r.avgGain = (r.avgGain*(p-1) + gain) / p
+		r.avgLoss = (r.avgLoss*(p-1) + loss) / p
+	}
+
+	switch {
+	case r.avgLoss == 0 && r.avgGain == 0:
+		r.value = 50
+	case r.avgLoss == 0:
+		r.value = 100
+	default:
+		r.value = 100 - 100/(1+r.avgGain/r.avgLoss)
+	}
+	return r.value
+}
+
+// Value is 50 until Period changes have been seen
+func (r *RSI) Value() float64 {
+	return r.value
+}
+
+func (r *RSI) Ready() bool {
+	return r.changes >= r.Period
+}
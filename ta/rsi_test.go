@@ -0,0 +1,45 @@
+package ta
+
+import "testing"
+
+// the RSI(14) example from StockCharts' "Relative Strength Index (RSI)"
+func TestRSI(t *testing.T) {
+	tests := []struct {
+		name   string
+		period int
+		closes []float64
+		want   []float64
+	}{
+		{
+			name:   "stockcharts",
+			period: 14,
+			closes: []float64{
+				44.3389, 44.0902, 44.1497, 43.6124, 44.3278, 44.8264, 45.0955, 45.4245, 45.8433, 46.0826,
+				45.8931, 46.0328, 45.6140, 46.2820, 46.2820, 46.0028, 46.0328, 46.4116, 46.2222, 45.6439,
+				46.2122, 46.2521, 45.7137, 46.4515, 45.7835, 45.3548, 44.0288, 44.1783, 44.2181, 44.5672,
+				43.4205, 42.6628, 43.1314,
+			},
+			want: warmup(14,
+				70.53, 66.32, 66.55, 69.41, 66.36, 57.97, 62.93, 63.26, 56.06, 62.38,
+				54.71, 50.42, 39.99, 41.46, 41.87, 45.46, 37.30, 33.08, 37.77),
+		},
+		{
+			name:   "only gains",
+			period: 3,
+			closes: []float64{1, 2, 3, 4, 5},
+			want:   warmup(3, 100, 100),
+		},
+		{
+			name:   "flat",
+			period: 3,
+			closes: []float64{2, 2, 2, 2},
+			want:   warmup(3, 50),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkSeries(t, "RSI", Series(NewRSI(tt.period), tt.closes), tt.want, 0.01)
+		})
+	}
+}
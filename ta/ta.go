@@ -0,0 +1,72 @@
+// Package ta has technical indicators that update in constant time per bar, so they can run on a live stream
+// of candles as well as over a whole series. Each is built with its period and fed bars oldest first.
+package ta
+
+// Bar is the part of a candle the indicators read
+type Bar struct {
+	Open, High, Low, Close float64
+	Volume                 float64
+}
+
+// Indicator is implemented by the indicators that only read closes
+type Indicator interface {
+	// Update adds the next close and returns the indicator's new value
+	Update(close float64) float64
+	Value() float64
+	// Ready is false until enough closes have been seen to fill the period
+	Ready() bool
+}
+
+// Series runs an indicator over values and returns its value after each one
+func Series(ind Indicator, values []float64) []float64 {
+	out := make([]float64, len(values))
+	for i, v := range values {
+		out[i] = ind.Update(v)
+	}
+	return out
+}
+
+// window keeps the last n values with their sum and sum of squares
+type window struct {
+	values     []float64
+	next, n    int
+	sum, sumSq float64
+	full       bool
+}
+
+func newWindow(n int) *window {
+	return &window{values: make([]float64, max(n, 1)), n: max(n, 1)}
+}
+
+// push adds v and returns the value it pushed out, ok is false while the window is still filling
+func (w *window) push(v float64) (old float64, ok bool) {
+	if w.full {
+		old, ok = w.values[w.next], true
+		w.sum -= old
+		w.sumSq -= old * old
+	}
+	w.values[w.next] = v
+	w.sum += v
+	w.sumSq += v * v
+
+	w.next++
+	if w.next == w.n {
+		w.next = 0
+		w.full = true
+	}
+	return
+}
+
+func (w *window) count() int {
+	if w.full {
+		return w.n
+	}
+	return w.next
+}
+
+func (w *window) mean() float64 {
+	if c := w.count(); c > 0 {
+		return w.sum / float64(c)
+	}
+	return 0
+}
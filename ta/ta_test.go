@@ -0,0 +1,32 @@
+package ta
+
+import (
+	"math"
+	"testing"
+)
+
+// checkSeries compares got to want from the first index want has a value for, nan marks a warm-up bar
+func checkSeries(t *testing.T, name string, got, want []float64, tolerance float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d values, want %d", name, len(got), len(want))
+	}
+	for i := range want {
+		if math.IsNaN(want[i]) {
+			continue
+		}
+		if math.Abs(got[i]-want[i]) > tolerance {
+			t.Errorf("%s[%d] = %.6f, want %.6f", name, i, got[i], want[i])
+		}
+	}
+}
+
+var nan = math.NaN()
+
+func warmup(n int, values ...float64) []float64 {
+	out := make([]float64, n, n+len(values))
+	for i := range out {
+		out[i] = nan
+	}
+	return append(out, values...)
+}
@@ -0,0 +1,79 @@
+package ta
+
+// VWAP is the volume weighted average of the typical price (high+low+close)/3 over the last Period bars,
+// a Period of zero weighs every bar since the start
+type VWAP struct {
+	Period        int
+	priceVolume   *window
+	volume        *window
+	pvSum, volSum float64
+	bars          int
+}
+
+func NewVWAP(period int) *VWAP {
+	v := &VWAP{Period: period}
+	if period > 0 {
+		v.priceVolume, v.volume = newWindow(period), newWindow(period)
+	}
+	return v
+}
+
+func (v *VWAP) Update(bar Bar) float64 {
+	typical := (bar.High + bar.Low + bar.Close) / 3
+	v.bars++
+	if v.Period > 0 {
+		v.priceVolume.push(typical * bar.Volume)
+		v.volume.push(bar.Volume)
+		v.pvSum, v.volSum = v.priceVolume.sum, v.volume.sum
+	} else {
+		v.pvSum += typical * bar.Volume
+		v.volSum += bar.Volume
+	}
+	return v.Value()
+}
+
+// Value is zero until some volume has traded
+func (v *VWAP) Value() float64 {
+	if v.volSum == 0 {
+		return 0
+	}
+	return v.pvSum / v.volSum
+}
+
+func (v *VWAP) Ready() bool {
+	return v.bars >= v.Period && v.bars > 0
+}
+
+// OBV is on-balance volume, a running total adding a bar's volume when it closes up and taking it when it closes down.
+// It has no period, its slope over time is what's read.
+type OBV struct {
+	prevClose float64
+	bars      int
+	value     float64
+}
+
+func NewOBV() *OBV {
+	return &OBV{}
+}
+
+func (o *OBV) Update(bar Bar) float64 {
+	if o.bars > 0 {
+		switch {
+		case bar.Close > o.prevClose:
+			o.value += bar.Volume
+		case bar.Close < o.prevClose:
+			o.value -= bar.Volume
+		}
+	}
+	o.prevClose = bar.Close
+	o.bars++
+	return o.value
+}
+
+func (o *OBV) Value() float64 {
+	return o.value
+}
+
+func (o *OBV) Ready() bool {
+	return o.bars > 1
+}
@@ -0,0 +1,49 @@
+package ta
+
+import "testing"
+
+func TestVWAP(t *testing.T) {
+	// typical prices 9, 11 and 10
+	bars := []Bar{
+		{High: 10, Low: 8, Close: 9, Volume: 100},
+		{High: 12, Low: 10, Close: 11, Volume: 300},
+		{High: 11, Low: 9, Close: 10, Volume: 100},
+	}
+	tests := []struct {
+		name   string
+		period int
+		want   []float64
+	}{
+		{"rolling", 2, []float64{9, 10.5, 10.75}},
+		{"cumulative", 0, []float64{9, 10.5, 10.4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vwap := NewVWAP(tt.period)
+			got := make([]float64, len(bars))
+			for i, bar := range bars {
+				got[i] = vwap.Update(bar)
+			}
+			checkSeries(t, "VWAP", got, tt.want, 1e-9)
+		})
+	}
+}
+
+func TestOBV(t *testing.T) {
+	bars := []Bar{
+		{Close: 10, Volume: 100},
+		{Close: 11, Volume: 200},
+		{Close: 10.5, Volume: 150},
+		{Close: 10.5, Volume: 50},
+		{Close: 12, Volume: 300},
+	}
+	want := []float64{0, 200, 50, 50, 350}
+
+	obv := NewOBV()
+	got := make([]float64, len(bars))
+	for i, bar := range bars {
+		got[i] = obv.Update(bar)
+	}
+	checkSeries(t, "OBV", got, want, 0)
+}
@@ -0,0 +1,132 @@
+package tape
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/codingsandmore/pumpfun-portal/portal"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/patrickmn/go-cache"
+	"trader.fun/pumpfun"
+)
+
+// getMultipleAccounts takes at most 100 keys per call
+const maxAccountsPerCall = 100
+
+// Recorder writes every pair and trade from the feed and snapshots the curves of recently active mints
+type Recorder struct {
+	Writer           *Writer
+	Client           *rpc.Client
+	SnapshotInterval time.Duration
+	Recorded         atomic.Int64
+	active           *cache.Cache // mint -> bonding curve key
+}
+
+func (r *Recorder) OnPair(p *portal.NewPairResponse) {
+	r.track(p.Mint, p.BondingCurveKey)
+	r.write(&Event{Time: time.Now(), Pair: p})
+}
+
+func (r *Recorder) OnTrade(p *portal.NewTradeResponse) {
+	r.track(p.Mint, p.BondingCurveKey)
+	r.write(&Event{Time: time.Now(), Trade: p})
+}
+
+func (r *Recorder) track(mint, bondingCurve string) {
+	if len(bondingCurve) == 0 {
+		// derive it for events that came without one
+		mintAddr, err := solana.PublicKeyFromBase58(mint)
+		if err != nil {
+			return
+		}
+		coin, err := pumpfun.NewCoin(mintAddr)
+		if err != nil {
+			return
+		}
+		bondingCurve = coin.TokenBondingCurve.String()
+	}
+	r.active.Set(mint, bondingCurve, cache.DefaultExpiration)
+}
+
+func (r *Recorder) write(e *Event) {
+	if err := r.Writer.Write(e); err != nil {
+		fmt.Println("Recorder:", err)
+		return
+	}
+	r.Recorded.Add(1)
+}
+
+// RunSnapshots snapshots the active curves and flushes the tape every interval until ctx is cancelled
+func (r *Recorder) RunSnapshots(ctx context.Context) {
+	ticker := time.NewTicker(r.SnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := r.snapshot(ctx); err != nil {
+			fmt.Println("Recorder:", err)
+		}
+		if err := r.Writer.Flush(); err != nil {
+			fmt.Println("Recorder:", err)
+		}
+	}
+}
+
+func (r *Recorder) snapshot(ctx context.Context) error {
+	var mints []string
+	var curves []solana.PublicKey
+	for mint, item := range r.active.Items() {
+		curve, err := solana.PublicKeyFromBase58(item.Object.(string))
+		if err != nil {
+			continue
+		}
+		mints = append(mints, mint)
+		curves = append(curves, curve)
+	}
+
+	for start := 0; start < len(curves); start += maxAccountsPerCall {
+		end := min(start+maxAccountsPerCall, len(curves))
+		accounts, err := r.Client.GetMultipleAccountsWithOpts(ctx, curves[start:end], &rpc.GetMultipleAccountsOpts{
+			Commitment: rpc.CommitmentConfirmed,
+		})
+		if err != nil {
+			return fmt.Errorf("error getting bonding curves: %v", err)
+		}
+
+		received := time.Now()
+		for i, account := range accounts.Value {
+			if account == nil {
+				continue
+			}
+			curve, err := pumpfun.DecodeBondingData(account.Data.GetBinary())
+			if err != nil {
+				continue
+			}
+			r.write(&Event{Time: received, Snapshot: &Snapshot{
+				Mint:         mints[start+i],
+				BondingCurve: curves[start+i].String(),
+				Slot:         accounts.Context.Slot,
+				Curve:        *curve,
+			}})
+		}
+	}
+
+	return nil
+}
+
+func NewRecorder(writer *Writer, client *rpc.Client) *Recorder {
+	return &Recorder{
+		Writer:           writer,
+		Client:           client,
+		SnapshotInterval: 10 * time.Second,
+		active:           cache.New(5*time.Minute, 5*time.Minute),
+	}
+}
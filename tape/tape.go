@@ -0,0 +1,113 @@
+package tape
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codingsandmore/pumpfun-portal/portal"
+	"trader.fun/pumpfun"
+)
+
+// Event is one recorded message from the feed, Time is when we received it
+type Event struct {
+	Time     time.Time                `json:"time"`
+	Pair     *portal.NewPairResponse  `json:"pair,omitempty"`
+	Trade    *portal.NewTradeResponse `json:"trade,omitempty"`
+	Snapshot *Snapshot                `json:"snapshot,omitempty"`
+}
+
+// Snapshot is a bonding curve account read straight from the chain
+type Snapshot struct {
+	Mint         string               `json:"mint"`
+	BondingCurve string               `json:"bondingCurve"`
+	Slot         uint64               `json:"slot"`
+	Curve        pumpfun.BondingCurve `json:"curve"`
+}
+
+// Files lists the tapes under path in time order, path can also be a single file
+func Files(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && (strings.HasSuffix(p, ".jsonl") || strings.HasSuffix(p, ".jsonl.gz")) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	// file names are time partitioned so lexical order is time order
+	sort.Strings(files)
+
+	return files, err
+}
+
+// Replay calls fn for every event in the tapes under path, stopping at the first error
+func Replay(path string, fn func(e *Event) error) error {
+	files, err := Files(path)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := replayFile(file, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func replayFile(path string, fn func(e *Event) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("error opening %s: %v", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("error decoding %s line %d: %v", path, line, err)
+		}
+		if err := fn(&e); err != nil {
+			return err
+		}
+	}
+
+	// a tape cut off mid write still replays up to the last whole line
+	if err := scanner.Err(); err != nil && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("error reading %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package tape
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Writer appends events to gzipped jsonl files partitioned by the hour, dir/2006/01/02/15.jsonl.gz
+type Writer struct {
+	dir       string
+	partition string
+	file      *os.File
+	gz        *gzip.Writer
+	lock      sync.Mutex
+}
+
+// Write stamps the event with the receive time if it has none and appends it to the current partition
+func (w *Writer) Write(e *Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error marshalling tape event: %v", err)
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if err := w.rotate(e.Time.UTC()); err != nil {
+		return err
+	}
+	if _, err := w.gz.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing tape event: %v", err)
+	}
+	return nil
+}
+
+func (w *Writer) rotate(t time.Time) error {
+	partition := filepath.Join(w.dir, t.Format("2006/01/02/15")) + ".jsonl.gz"
+	if partition == w.partition {
+		return nil
+	}
+
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(partition), 0755); err != nil {
+		return fmt.Errorf("error creating tape directory: %v", err)
+	}
+
+	// restarting inside the same hour appends a new gzip member, readers see one stream
+	file, err := os.OpenFile(partition, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening tape: %v", err)
+	}
+
+	w.partition = partition
+	w.file = file
+	w.gz = gzip.NewWriter(file)
+	return nil
+}
+
+func (w *Writer) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	if err := w.gz.Close(); err != nil {
+		return fmt.Errorf("error closing tape: %v", err)
+	}
+	file := w.file
+	w.partition, w.file, w.gz = "", nil, nil
+	return file.Close()
+}
+
+// Flush pushes buffered events to disk, a crash loses at most what was written since
+func (w *Writer) Flush() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.gz == nil {
+		return nil
+	}
+	return w.gz.Flush()
+}
+
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.closeFile()
+}
+
+func NewWriter(dir string) *Writer {
+	return &Writer{dir: dir}
+}
@@ -0,0 +1,51 @@
+package trader
+
+import (
+	"errors"
+
+	"trader.fun/position"
+	"trader.fun/pumpfun"
+	"trader.fun/strategy"
+	"trader.fun/wallet"
+)
+
+var ErrHalted = errors.New("total stop loss hit, not opening new trades")
+
+// Live places real orders through a SolWallet, the manager's total stop loss blocks new buys
+type Live struct {
+	Wallet   *wallet.SolWallet
+	Manager  *position.Manager
+	Slippage float64
+	Prices   *pumpfun.PriceFeed // optional, position prices are read from the rpc without it
+}
+
+func (l *Live) Buy(coin *pumpfun.Coin, solAmount float64) error {
+	if l.Manager != nil && l.Manager.Halted() {
+		return ErrHalted
+	}
+	_, err := l.Wallet.BuyToken(coin, solAmount, l.Slippage)
+	return err
+}
+
+func (l *Live) Sell(coin *pumpfun.Coin, percentage float64) error {
+	_, err := l.Wallet.SellToken(coin, percentage, l.Slippage)
+	return err
+}
+
+func (l *Live) Positions() []strategy.Position {
+	var positions []strategy.Position
+	for _, pos := range l.Wallet.Ledger.Open() {
+		positions = append(positions, strategy.Position{
+			Coin:   pos.Coin(),
+			Amount: pos.Amount(),
+			Entry:  pos.EntryPrice(),
+			Price:  spotPrice(l.Wallet.RpcClient, l.Prices, pos.TokenBondingCurve),
+			Opened: pos.Opened(),
+		})
+	}
+	return positions
+}
+
+func (l *Live) SolBalance() (float64, error) {
+	return l.Wallet.SolBalance()
+}
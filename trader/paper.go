@@ -0,0 +1,122 @@
+package trader
+
+import (
+	"context"
+	"math"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"trader.fun/journal"
+	"trader.fun/papertrade"
+	"trader.fun/pumpfun"
+	"trader.fun/strategy"
+)
+
+// Paper fills orders in a papertrade wallet against the live bonding curves
+type Paper struct {
+	Wallet   *papertrade.Wallet
+	Client   *rpc.Client
+	Journal  *journal.Journal
+	Slippage float64
+	Prices   *pumpfun.PriceFeed // optional, curves are read from the rpc without it
+}
+
+func (p *Paper) Buy(coin *pumpfun.Coin, solAmount float64) error {
+	curve, err := p.curve(coin.TokenBondingCurve)
+	if err != nil {
+		return err
+	}
+
+	fill, err := p.Wallet.Buy(coin, curve, solAmount, p.Slippage)
+	if err != nil {
+		return err
+	}
+
+	p.Journal.Record(fillEvent(coin, journal.Buy, fill))
+	return nil
+}
+
+func (p *Paper) Sell(coin *pumpfun.Coin, percentage float64) error {
+	curve, err := p.curve(coin.TokenBondingCurve)
+	if err != nil {
+		return err
+	}
+
+	var solCost uint64
+	for _, pos := range p.Wallet.Open() {
+		if pos.Coin.MintAddr.Equals(coin.MintAddr) {
+			solCost = pos.SolCost
+		}
+	}
+
+	fill, err := p.Wallet.Sell(coin, curve, percentage, p.Slippage)
+	if err != nil {
+		return err
+	}
+
+	e := fillEvent(coin, journal.Sell, fill)
+	p.Journal.Record(e)
+	if percentage >= 100 {
+		e.Type, e.PnL = journal.PositionClosed, lamportsToSol(fill.Lamports)-lamportsToSol(solCost)
+		p.Journal.Record(e)
+	}
+	return nil
+}
+
+func (p *Paper) Positions() []strategy.Position {
+	var positions []strategy.Position
+	for _, pos := range p.Wallet.Open() {
+		amount := float64(pos.Tokens) / math.Pow10(pumpfun.TokenDecimals)
+		positions = append(positions, strategy.Position{
+			Coin:   pos.Coin,
+			Amount: amount,
+			Entry:  lamportsToSol(pos.SolCost) / amount,
+			Price:  spotPrice(p.Client, p.Prices, pos.Coin.TokenBondingCurve),
+			Opened: pos.Opened,
+		})
+	}
+	return positions
+}
+
+func (p *Paper) SolBalance() (float64, error) {
+	return p.Wallet.SolBalance(), nil
+}
+
+func fillEvent(coin *pumpfun.Coin, side journal.Side, fill *papertrade.Fill) journal.Event {
+	return journal.Event{
+		Type:         journal.OrderConfirmed,
+		Mint:         coin.MintAddr,
+		BondingCurve: coin.TokenBondingCurve,
+		Side:         side,
+		Sol:          lamportsToSol(fill.Lamports),
+		Tokens:       float64(fill.Tokens) / math.Pow10(pumpfun.TokenDecimals),
+	}
+}
+
+func (p *Paper) curve(bondingCurve solana.PublicKey) (*pumpfun.BondingCurve, error) {
+	return latestCurve(p.Client, p.Prices, bondingCurve)
+}
+
+// latestCurve is the feed's latest state of the curve, or the rpc's until the feed has one
+func latestCurve(client *rpc.Client, prices *pumpfun.PriceFeed, bondingCurve solana.PublicKey) (*pumpfun.BondingCurve, error) {
+	if prices != nil {
+		if update, ok := prices.Latest(bondingCurve); ok {
+			return update.Curve, nil
+		}
+		prices.Watch(bondingCurve)
+	}
+	return pumpfun.GetBondingCurveInfos(context.Background(), client, bondingCurve)
+}
+
+// spotPrice is zero when the curve can't be read, strategies treat that as unknown
+func spotPrice(client *rpc.Client, prices *pumpfun.PriceFeed, bondingCurve solana.PublicKey) float64 {
+	curve, err := latestCurve(client, prices, bondingCurve)
+	if err != nil {
+		return 0
+	}
+	return curve.SpotPrice()
+}
+
+func lamportsToSol(lamports uint64) float64 {
+	return float64(lamports) / float64(solana.LAMPORTS_PER_SOL)
+}
@@ -0,0 +1,350 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/codingsandmore/pumpfun-portal/portal"
+	"github.com/gagliardetto/solana-go"
+	"trader.fun/journal"
+	"trader.fun/pumpfun"
+	"trader.fun/strategy"
+)
+
+// Pool feeds market events to one strategy and schedules its intents over several traders.
+// A mint belongs to at most one trader, from the buy until its position is gone.
+type Pool struct {
+	Strategy     strategy.Strategy
+	Traders      []*Trader
+	Journal      *journal.Journal
+	TickInterval time.Duration
+	Flatten      bool // sell everything on shutdown instead of leaving positions open
+	// Prices, when set, follows the curves of open positions and passes their updates to a strategy.PriceListener
+	Prices *pumpfun.PriceFeed
+
+	owners   map[solana.PublicKey]*Trader
+	pending  map[solana.PublicKey]bool // buys not yet seen in the trader's positions
+	start    map[*Trader]float64       // equity at the first report
+	closed   bool
+	lock     sync.Mutex
+	strategy sync.Mutex // strategies aren't safe for concurrent use, only held while one runs
+	workers  sync.WaitGroup
+	confirms chan struct{} // a slot per running Confirm, intents that find none are dropped rather than queued
+}
+
+// Report is one trader's balance and open positions, Total in a PoolReport sums them
+type Report struct {
+	Name      string
+	Sol       float64
+	Positions []strategy.Position
+	Equity    float64 // sol plus open positions at their latest price
+	PnL       float64 // equity change since the pool started
+}
+
+type PoolReport struct {
+	Traders []Report
+	Total   Report
+}
+
+func (p *Pool) OnNewPair(pair *portal.NewPairResponse) {
+	p.strategy.Lock()
+	intents := p.Strategy.OnNewPair(pair)
+	p.strategy.Unlock()
+
+	p.schedule(intents, false)
+}
+
+func (p *Pool) OnTrade(trade *portal.NewTradeResponse) {
+	p.strategy.Lock()
+	intents := p.Strategy.OnTrade(trade)
+	p.strategy.Unlock()
+
+	p.schedule(intents, false)
+}
+
+// Run starts the traders and ticks the strategy with every trader's positions until ctx is cancelled
+func (p *Pool) Run(ctx context.Context) {
+	for _, t := range p.Traders {
+		p.workers.Add(1)
+		go func() {
+			defer p.workers.Done()
+			t.run(p.done)
+		}()
+	}
+
+	ticker := time.NewTicker(p.TickInterval)
+	defer ticker.Stop()
+
+	var updates <-chan pumpfun.CurveUpdate
+	if p.Prices != nil {
+		var stop func()
+		updates, stop = p.Prices.Subscribe()
+		defer stop()
+	}
+	listener, _ := p.Strategy.(strategy.PriceListener)
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.shutdown()
+			return
+		case update := <-updates:
+			if listener == nil {
+				continue
+			}
+			p.strategy.Lock()
+			intents := listener.OnPrice(update)
+			p.strategy.Unlock()
+
+			p.schedule(intents, false)
+		case now := <-ticker.C:
+			positions := p.positions()
+
+			p.strategy.Lock()
+			intents := p.Strategy.OnTick(now, positions)
+			p.strategy.Unlock()
+
+			p.schedule(intents, false)
+		}
+	}
+}
+
+// positions gathers every trader's positions, adopting ones the pool doesn't know about yet
+// and releasing mints whose position is gone
+func (p *Pool) positions() (all []strategy.Position) {
+	held := make(map[solana.PublicKey]*Trader)
+	for _, t := range p.Traders {
+		for _, pos := range t.Broker.Positions() {
+			held[pos.Coin.MintAddr] = t
+			all = append(all, pos)
+			if p.Prices != nil {
+				p.Prices.Watch(pos.Coin.TokenBondingCurve)
+			}
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for mint, t := range p.owners {
+		if held[mint] != t && !p.pending[mint] {
+			delete(p.owners, mint)
+		}
+	}
+	for mint, t := range held {
+		p.owners[mint] = t
+		delete(p.pending, mint)
+	}
+	return
+}
+
+// confirm runs each intent's Confirm on its own goroutine, outside the strategy lock and off the feed,
+// and schedules the intents it lets through. The rest are returned to be scheduled straight away.
+func (p *Pool) confirm(intents []strategy.Intent) (ready []strategy.Intent) {
+	for _, intent := range intents {
+		if intent.Confirm == nil {
+			ready = append(ready, intent)
+			continue
+		}
+		// don't spend a slot on a buy schedule would turn down anyway
+		p.lock.Lock()
+		owned := p.owners[intent.Coin.MintAddr] != nil
+		p.lock.Unlock()
+		if intent.Side == strategy.Buy && owned {
+			continue
+		}
+
+		select {
+		case p.confirms <- struct{}{}:
+		default:
+			fmt.Printf("Pool: confirmers busy, dropping %s %s\n", intent.Side, intent.Coin.MintAddr.String())
+			continue
+		}
+		go func() {
+			defer func() { <-p.confirms }()
+			if intent.Confirm() {
+				intent.Confirm = nil
+				p.schedule([]strategy.Intent{intent}, false)
+			}
+		}()
+	}
+	return
+}
+
+// schedule routes buys to the least loaded trader with room and sells to the owner of the mint.
+// Feed events never block, wait is for shutdown where every sell has to go through.
+func (p *Pool) schedule(intents []strategy.Intent, wait bool) {
+	intents = p.confirm(intents)
+
+	type routed struct {
+		trader *Trader
+		intent strategy.Intent
+	}
+	var queue []routed
+
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return
+	}
+
+	// several exit rules can fire on the same tick, sell each mint once
+	sold := make(map[solana.PublicKey]bool)
+	for _, intent := range intents {
+		mint := intent.Coin.MintAddr
+		owner := p.owners[mint]
+
+		var t *Trader
+		switch intent.Side {
+		case strategy.Buy:
+			if owner != nil {
+				continue
+			}
+			if t = p.leastLoaded(); t == nil {
+				continue
+			}
+		case strategy.Sell:
+			if owner == nil || sold[mint] {
+				continue
+			}
+			t, sold[mint] = owner, true
+		}
+
+		p.Journal.Record(journal.Event{
+			Type:         journal.Signal,
+			Strategy:     p.Strategy.Name(),
+			Mint:         mint,
+			BondingCurve: intent.Coin.TokenBondingCurve,
+			Side:         journal.Side(intent.Side),
+			Reason:       intent.Reason,
+		})
+
+		// a blocking send can't hold the lock, the trader needs it to report back
+		if wait {
+			queue = append(queue, routed{t, intent})
+			continue
+		}
+
+		select {
+		case t.intents <- intent:
+		default:
+			fmt.Printf("Trader %s: busy, dropping %s %s\n", t.Name, intent.Side, mint.String())
+			continue
+		}
+		if intent.Side == strategy.Buy {
+			p.owners[mint], p.pending[mint] = t, true
+		}
+	}
+	p.lock.Unlock()
+
+	for _, r := range queue {
+		r.trader.intents <- r.intent
+	}
+}
+
+// leastLoaded is the trader with the fewest mints that still has room, nil when all are full
+func (p *Pool) leastLoaded() (least *Trader) {
+	load := make(map[*Trader]int)
+	for _, t := range p.owners {
+		load[t]++
+	}
+	for _, t := range p.Traders {
+		if t.MaxPositions > 0 && load[t] >= t.MaxPositions {
+			continue
+		}
+		if least == nil || load[t] < load[least] {
+			least = t
+		}
+	}
+	return
+}
+
+func (p *Pool) done(t *Trader, intent strategy.Intent, err error) {
+	if err != nil {
+		fmt.Printf("Trader %s: %v\n", t.Name, err)
+	}
+	// a filled buy stays pending until positions sees it, so a snapshot taken before the fill can't release it
+	if intent.Side != strategy.Buy || err == nil {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.pending, intent.Coin.MintAddr)
+	if p.owners[intent.Coin.MintAddr] == t {
+		delete(p.owners, intent.Coin.MintAddr)
+	}
+}
+
+// shutdown flattens when asked to, then lets the traders finish what they have queued.
+// Only sells are scheduled with wait, so there are no buy claims to track for them.
+func (p *Pool) shutdown() {
+	if p.Flatten {
+		var sells []strategy.Intent
+		for _, pos := range p.positions() {
+			sells = append(sells, strategy.Intent{Side: strategy.Sell, Coin: pos.Coin, Percentage: 100, Reason: "shutdown"})
+		}
+		p.schedule(sells, true)
+	}
+
+	p.lock.Lock()
+	p.closed = true
+	for _, t := range p.Traders {
+		close(t.intents)
+	}
+	p.lock.Unlock()
+
+	p.workers.Wait()
+
+	if !p.Flatten {
+		for _, pos := range p.positions() {
+			fmt.Printf("Pool: leaving %s open, %.2f tokens at %.10f sol\n", pos.Coin.MintAddr.String(), pos.Amount, pos.Entry)
+		}
+	}
+}
+
+// Report aggregates balances, positions and PnL over the traders
+func (p *Pool) Report() PoolReport {
+	var report PoolReport
+	report.Total.Name = "total"
+
+	for _, t := range p.Traders {
+		r := Report{Name: t.Name, Positions: t.Broker.Positions()}
+		r.Sol, _ = t.Broker.SolBalance()
+		r.Equity = r.Sol
+		for _, pos := range r.Positions {
+			r.Equity += pos.Amount * pos.Price
+		}
+
+		p.lock.Lock()
+		if _, ok := p.start[t]; !ok {
+			p.start[t] = r.Equity
+		}
+		r.PnL = r.Equity - p.start[t]
+		p.lock.Unlock()
+
+		report.Traders = append(report.Traders, r)
+		report.Total.Sol += r.Sol
+		report.Total.Equity += r.Equity
+		report.Total.PnL += r.PnL
+		report.Total.Positions = append(report.Total.Positions, r.Positions...)
+	}
+
+	return report
+}
+
+func NewPool(s strategy.Strategy, traders []*Trader, j *journal.Journal) *Pool {
+	return &Pool{
+		Strategy:     s,
+		Traders:      traders,
+		Journal:      j,
+		TickInterval: 1 * time.Second,
+		owners:       make(map[solana.PublicKey]*Trader),
+		pending:      make(map[solana.PublicKey]bool),
+		start:        make(map[*Trader]float64),
+		confirms:     make(chan struct{}, 4),
+	}
+}
@@ -0,0 +1,66 @@
+package trader
+
+import (
+	"fmt"
+
+	"trader.fun/pumpfun"
+	"trader.fun/strategy"
+)
+
+// Broker places orders for a trader, it's a paper wallet or a real one
+type Broker interface {
+	Buy(coin *pumpfun.Coin, solAmount float64) error
+	Sell(coin *pumpfun.Coin, percentage float64) error
+	Positions() []strategy.Position
+	SolBalance() (float64, error)
+}
+
+// Trader executes the intents a pool schedules for it through its own broker, one at a time
+type Trader struct {
+	Name         string
+	Broker       Broker
+	BalanceRisk  float64 // percent of this trader's sol balance per buy
+	MaxPositions int
+
+	intents chan strategy.Intent
+}
+
+// run executes intents until the pool closes the queue, done is told whether each one went through
+func (t *Trader) run(done func(t *Trader, intent strategy.Intent, err error)) {
+	for intent := range t.intents {
+		done(t, intent, t.execute(intent))
+	}
+}
+
+func (t *Trader) execute(intent strategy.Intent) error {
+	coin := intent.Coin
+	switch intent.Side {
+	case strategy.Buy:
+		solAmount := intent.Sol
+		if solAmount == 0 {
+			balance, err := t.Broker.SolBalance()
+			if err != nil {
+				return err
+			}
+			solAmount = balance * (t.BalanceRisk / 100)
+		}
+
+		if err := t.Broker.Buy(&coin, solAmount); err != nil {
+			return fmt.Errorf("could not buy %s: %v", coin.MintAddr.String(), err)
+		}
+	case strategy.Sell:
+		if err := t.Broker.Sell(&coin, intent.Percentage); err != nil {
+			return fmt.Errorf("could not sell %s on %s: %v", coin.MintAddr.String(), intent.Reason, err)
+		}
+	}
+	return nil
+}
+
+func New(name string, broker Broker) *Trader {
+	return &Trader{
+		Name:         name,
+		Broker:       broker,
+		MaxPositions: 1,
+		intents:      make(chan strategy.Intent, 16),
+	}
+}
@@ -0,0 +1,89 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Blockhash is a recent blockhash and the last block height a transaction using it can land in
+type Blockhash struct {
+	Hash                 solana.Hash
+	LastValidBlockHeight uint64
+	Fetched              time.Time
+}
+
+// BlockhashCache keeps a confirmed blockhash fresh so orders don't wait on the rpc for one.
+// Run refreshes it in the background, without Run every Get older than MaxAge fetches a new one.
+type BlockhashCache struct {
+	Client   *rpc.Client
+	Interval time.Duration // between background refreshes
+	MaxAge   time.Duration // a cached hash older than this is fetched again
+
+	latest *Blockhash
+	lock   sync.Mutex
+}
+
+// Get returns the cached blockhash, fetching one when it's missing or stale
+func (c *BlockhashCache) Get(ctx context.Context) (*Blockhash, error) {
+	c.lock.Lock()
+	latest := c.latest
+	c.lock.Unlock()
+
+	if latest != nil && time.Since(latest.Fetched) < c.MaxAge {
+		return latest, nil
+	}
+	return c.refresh(ctx)
+}
+
+// Run refreshes the blockhash every Interval until ctx is cancelled
+func (c *BlockhashCache) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.refresh(ctx); err != nil && ctx.Err() == nil {
+			fmt.Println("Blockhash:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *BlockhashCache) refresh(ctx context.Context) (*Blockhash, error) {
+	result, err := c.Client.GetLatestBlockhash(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("error getting latest blockhash: %v", err)
+	}
+	if result.Value == nil {
+		return nil, fmt.Errorf("error getting latest blockhash: empty result")
+	}
+
+	latest := &Blockhash{
+		Hash:                 result.Value.Blockhash,
+		LastValidBlockHeight: result.Value.LastValidBlockHeight,
+		Fetched:              time.Now(),
+	}
+
+	c.lock.Lock()
+	c.latest = latest
+	c.lock.Unlock()
+
+	return latest, nil
+}
+
+func NewBlockhashCache(client *rpc.Client) *BlockhashCache {
+	return &BlockhashCache{
+		Client:   client,
+		Interval: 2 * time.Second,
+		MaxAge:   10 * time.Second,
+	}
+}
@@ -0,0 +1,237 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// pump program error codes, see the program IDL
+const (
+	pumpTooMuchSolRequired   = 6002
+	pumpTooLittleSolReceived = 6003
+)
+
+var (
+	ErrBlockhashExpired  = errors.New("blockhash expired before the transaction landed")
+	ErrSlippageExceeded  = errors.New("price moved past the slippage tolerance")
+	ErrInsufficientFunds = errors.New("insufficient funds for transaction")
+	// ErrNotConfirmed is a transaction that was neither seen to land nor to expire, it may still land
+	ErrNotConfirmed = errors.New("transaction not confirmed yet, it may still land")
+)
+
+// TxError is a transaction that landed and failed, Err is one of the typed errors above when it's recognised
+type TxError struct {
+	Signature solana.Signature
+	Err       error
+	Raw       interface{}
+}
+
+func (e *TxError) Error() string {
+	return fmt.Sprintf("transaction %s failed: %v (%v)", e.Signature, e.Err, e.Raw)
+}
+
+func (e *TxError) Unwrap() error {
+	return e.Err
+}
+
+// Fill is what a confirmed transaction actually did to the wallet
+type Fill struct {
+	Signature  solana.Signature
+	Slot       uint64
+	TokenDelta float64 // change in ui token amount, positive on buys
+	SolDelta   float64 // change in sol balance including fees, negative on buys
+	// Estimated is set when the transaction couldn't be read after it landed, the token change then comes
+	// from the token account, or the quote when that can't be read either, and the sol change from the quote
+	Estimated bool
+}
+
+type Tracker struct {
+	Client      *rpc.Client
+	Interval    time.Duration
+	Timeout     time.Duration
+	Rebroadcast time.Duration // how often a transaction that hasn't been seen yet is sent again
+	FillTimeout time.Duration // how long AwaitFill keeps trying to read a confirmed transaction
+}
+
+// Send broadcasts the signed transaction once, the rpc doesn't retry it since Confirm does
+func (t *Tracker) Send(tx *solana.Transaction) (solana.Signature, error) {
+	maxRetries := uint(0)
+	return t.Client.SendTransactionWithOpts(context.Background(), tx, rpc.TransactionOpts{
+		SkipPreflight:       true,
+		PreflightCommitment: rpc.CommitmentConfirmed,
+		MaxRetries:          &maxRetries,
+	})
+}
+
+// Confirm polls the transaction until it's confirmed, fails on chain or the chain passes lastValidBlockHeight.
+// Until the rpc has seen it the transaction is rebroadcast every Rebroadcast.
+func (t *Tracker) Confirm(tx *solana.Transaction, lastValidBlockHeight uint64) error {
+	sig := tx.Signatures[0]
+	sent := time.Now()
+
+	deadline := time.Now().Add(t.Timeout)
+	for time.Now().Before(deadline) {
+		statuses, err := t.Client.GetSignatureStatuses(context.Background(), false, sig)
+		if err == nil && len(statuses.Value) > 0 && statuses.Value[0] != nil {
+			if done, err := settled(sig, statuses.Value[0]); done {
+				return err
+			}
+		} else if height, err := t.Client.GetBlockHeight(context.Background(), rpc.CommitmentConfirmed); err == nil && height > lastValidBlockHeight {
+			// one last look, it could have landed between the two calls. Only a successful read that doesn't
+			// find it proves it expired, on an rpc error it's polled again.
+			statuses, err := t.Client.GetSignatureStatuses(context.Background(), true, sig)
+			if err == nil && (len(statuses.Value) == 0 || statuses.Value[0] == nil) {
+				return ErrBlockhashExpired
+			}
+			if err == nil {
+				if done, err := settled(sig, statuses.Value[0]); done {
+					return err
+				}
+			}
+		} else if time.Since(sent) >= t.Rebroadcast {
+			if _, err := t.Send(tx); err != nil {
+				fmt.Println("Tracker: error rebroadcasting", sig, err)
+			}
+			sent = time.Now()
+		}
+		time.Sleep(t.Interval)
+	}
+
+	return fmt.Errorf("transaction %s was not confirmed within %s: %w", sig, t.Timeout, ErrNotConfirmed)
+}
+
+// settled reports whether the status decides the transaction, err is how it failed on chain if it did
+func settled(sig solana.Signature, status *rpc.SignatureStatusesResult) (done bool, err error) {
+	if status.Err != nil {
+		return true, &TxError{Signature: sig, Err: classifyTxError(status.Err), Raw: status.Err}
+	}
+	return status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed ||
+		status.ConfirmationStatus == rpc.ConfirmationStatusFinalized, nil
+}
+
+// Fill reads the confirmed transaction and returns the owner's real token and sol balance changes
+func (t *Tracker) Fill(sig solana.Signature, owner, mint solana.PublicKey) (*Fill, error) {
+	maxVersion := uint64(0)
+	tx, err := t.Client.GetTransaction(context.Background(), sig, &rpc.GetTransactionOpts{
+		Encoding:                       solana.EncodingBase64,
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting transaction %s: %v", sig, err)
+	}
+	if tx.Meta == nil {
+		return nil, fmt.Errorf("transaction %s has no meta", sig)
+	}
+	if tx.Meta.Err != nil {
+		return nil, &TxError{Signature: sig, Err: classifyTxError(tx.Meta.Err), Raw: tx.Meta.Err}
+	}
+
+	fill := &Fill{Signature: sig, Slot: tx.Slot}
+
+	// the wallet pays for the transaction so it's always the first account
+	if len(tx.Meta.PreBalances) > 0 && len(tx.Meta.PostBalances) > 0 {
+		fill.SolDelta = (float64(tx.Meta.PostBalances[0]) - float64(tx.Meta.PreBalances[0])) / float64(solana.LAMPORTS_PER_SOL)
+	}
+
+	fill.TokenDelta = tokenBalance(tx.Meta.PostTokenBalances, owner, mint) - tokenBalance(tx.Meta.PreTokenBalances, owner, mint)
+
+	return fill, nil
+}
+
+// AwaitFill retries Fill with backoff until it succeeds, the transaction turns out to have failed or FillTimeout passes.
+// A transaction can be reported confirmed before the rpc serves it from getTransaction.
+func (t *Tracker) AwaitFill(sig solana.Signature, owner, mint solana.PublicKey) (*Fill, error) {
+	deadline := time.Now().Add(t.FillTimeout)
+	backoff := t.Interval
+	for {
+		fill, err := t.Fill(sig, owner, mint)
+		var txErr *TxError
+		if err == nil || errors.As(err, &txErr) || time.Now().Add(backoff).After(deadline) {
+			return fill, err
+		}
+		time.Sleep(backoff)
+		backoff = min(backoff*2, 4*time.Second)
+	}
+}
+
+func tokenBalance(balances []rpc.TokenBalance, owner, mint solana.PublicKey) float64 {
+	for _, balance := range balances {
+		if balance.Owner == nil || !balance.Owner.Equals(owner) || !balance.Mint.Equals(mint) || balance.UiTokenAmount == nil {
+			continue
+		}
+		amount, err := strconv.ParseUint(balance.UiTokenAmount.Amount, 10, 64)
+		if err != nil {
+			return 0
+		}
+		return float64(amount) / math.Pow10(int(balance.UiTokenAmount.Decimals))
+	}
+	// no entry means the token account didn't exist before (or was closed after)
+	return 0
+}
+
+// classifyTxError maps the rpc's json transaction error onto our typed errors
+func classifyTxError(txErr interface{}) error {
+	switch e := txErr.(type) {
+	case string:
+		switch e {
+		case "BlockhashNotFound":
+			return ErrBlockhashExpired
+		case "InsufficientFundsForFee":
+			return ErrInsufficientFunds
+		}
+	case map[string]interface{}:
+		if _, ok := e["InsufficientFundsForRent"]; ok {
+			return ErrInsufficientFunds
+		}
+		instructionErr, ok := e["InstructionError"].([]interface{})
+		if !ok || len(instructionErr) != 2 {
+			break
+		}
+		detail, ok := instructionErr[1].(map[string]interface{})
+		if !ok {
+			break
+		}
+		code, ok := detail["Custom"]
+		if !ok {
+			break
+		}
+		switch customCode(code) {
+		case pumpTooMuchSolRequired, pumpTooLittleSolReceived:
+			return ErrSlippageExceeded
+		case 1: // system transfer and spl token both use 1 for insufficient funds
+			return ErrInsufficientFunds
+		}
+	}
+
+	return fmt.Errorf("%v", txErr)
+}
+
+func customCode(code interface{}) int64 {
+	switch c := code.(type) {
+	case float64:
+		return int64(c)
+	case json.Number:
+		n, _ := c.Int64()
+		return n
+	}
+	return -1
+}
+
+func NewTracker(client *rpc.Client) *Tracker {
+	return &Tracker{
+		Client:      client,
+		Interval:    500 * time.Millisecond,
+		Timeout:     90 * time.Second,
+		Rebroadcast: 2 * time.Second,
+		FillTimeout: 30 * time.Second,
+	}
+}
@@ -0,0 +1,24 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Derive deterministically derives the index'th trader key from a base key, the same base always gives the same wallets
+func Derive(base solana.PrivateKey, index int) (solana.PrivateKey, error) {
+	if len(base) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid base private key")
+	}
+
+	seed := sha256.New()
+	seed.Write(base[:ed25519.SeedSize])
+	seed.Write([]byte("trader.fun/trader"))
+	binary.Write(seed, binary.LittleEndian, uint32(index))
+
+	return solana.PrivateKey(ed25519.NewKeyFromSeed(seed.Sum(nil))), nil
+}
@@ -0,0 +1,134 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+	computeBudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// the most compute units a transaction can ask for
+const maxComputeUnits = 1_400_000
+
+// FeeEstimator prices compute units from what recently landed against the same accounts
+// and sizes the compute unit limit by simulating the transaction first
+type FeeEstimator struct {
+	Client       *rpc.Client
+	Percentile   float64 // of the recent per slot fees, 0-100
+	MaxFee       float64 // sol per transaction spent on priority, caps the price
+	MinPrice     uint64  // micro-lamports per compute unit when nothing recent paid more
+	Headroom     float64 // percent added on top of the simulated units
+	DefaultLimit uint32  // compute unit limit when simulation fails
+}
+
+// Budget returns instructions with the compute budget instructions prepended.
+// writable are the accounts the transaction locks that other traders are fighting over.
+func (f *FeeEstimator) Budget(ctx context.Context, instructions []solana.Instruction, writable []solana.PublicKey, payer solana.PublicKey, signer func(solana.PublicKey) *solana.PrivateKey) ([]solana.Instruction, error) {
+	limit, err := f.Limit(ctx, instructions, payer, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := f.Price(ctx, writable)
+	if err != nil {
+		fmt.Println("Fee estimator:", err)
+		price = f.MinPrice
+	}
+	price = f.capPrice(price, limit)
+
+	return append([]solana.Instruction{
+		computeBudget.NewSetComputeUnitPriceInstruction(price).Build(),
+		computeBudget.NewSetComputeUnitLimitInstruction(limit).Build(),
+	}, instructions...), nil
+}
+
+// Price is the configured percentile of recent prioritization fees in micro-lamports per compute unit
+func (f *FeeEstimator) Price(ctx context.Context, writable []solana.PublicKey) (uint64, error) {
+	recent, err := f.Client.GetRecentPrioritizationFees(ctx, writable)
+	if err != nil {
+		return 0, fmt.Errorf("error getting recent prioritization fees: %v", err)
+	}
+	if len(recent) == 0 {
+		return f.MinPrice, nil
+	}
+
+	fees := make([]uint64, len(recent))
+	for i, r := range recent {
+		fees[i] = r.PrioritizationFee
+	}
+	sort.Slice(fees, func(i, j int) bool { return fees[i] < fees[j] })
+
+	rank := int(math.Ceil(f.Percentile/100*float64(len(fees)))) - 1
+	rank = min(max(rank, 0), len(fees)-1)
+
+	return max(fees[rank], f.MinPrice), nil
+}
+
+// Limit simulates the instructions and returns the units they consumed plus headroom.
+// A simulation that fails on slippage or funds is returned as an error since the real one would fail too,
+// any other failure falls back to DefaultLimit.
+func (f *FeeEstimator) Limit(ctx context.Context, instructions []solana.Instruction, payer solana.PublicKey, signer func(solana.PublicKey) *solana.PrivateKey) (uint32, error) {
+	simulated := append([]solana.Instruction{
+		computeBudget.NewSetComputeUnitLimitInstruction(maxComputeUnits).Build(),
+	}, instructions...)
+
+	// the rpc swaps in a recent blockhash, so any hash will do
+	tx, err := solana.NewTransaction(simulated, solana.Hash{}, solana.TransactionPayer(payer))
+	if err != nil {
+		return 0, fmt.Errorf("error creating transaction: %v", err)
+	}
+	if _, err := tx.Sign(signer); err != nil {
+		return 0, fmt.Errorf("error signing transaction: %v", err)
+	}
+
+	result, err := f.Client.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		Commitment:             rpc.CommitmentProcessed,
+		ReplaceRecentBlockhash: true,
+	})
+	if err != nil {
+		fmt.Println("Fee estimator: error simulating transaction:", err)
+		return f.DefaultLimit, nil
+	}
+	if result.Value == nil {
+		return f.DefaultLimit, nil
+	}
+	if result.Value.Err != nil {
+		if err := classifyTxError(result.Value.Err); errors.Is(err, ErrSlippageExceeded) || errors.Is(err, ErrInsufficientFunds) {
+			return 0, fmt.Errorf("simulation failed: %w (%v)", err, result.Value.Err)
+		}
+		fmt.Println("Fee estimator: simulation failed:", result.Value.Err)
+		return f.DefaultLimit, nil
+	}
+	if result.Value.UnitsConsumed == nil || *result.Value.UnitsConsumed == 0 {
+		return f.DefaultLimit, nil
+	}
+
+	limit := float64(*result.Value.UnitsConsumed) * (1 + f.Headroom/100)
+	return uint32(min(math.Ceil(limit), maxComputeUnits)), nil
+}
+
+// capPrice keeps price * limit within MaxFee
+func (f *FeeEstimator) capPrice(price uint64, limit uint32) uint64 {
+	if f.MaxFee <= 0 || limit == 0 {
+		return price
+	}
+	// the price is in micro-lamports per unit
+	maxPrice := uint64(f.MaxFee * float64(solana.LAMPORTS_PER_SOL) * 1e6 / float64(limit))
+	return min(price, maxPrice)
+}
+
+func NewFeeEstimator(client *rpc.Client) *FeeEstimator {
+	return &FeeEstimator{
+		Client:       client,
+		Percentile:   75,
+		MaxFee:       0.001,
+		MinPrice:     10_000,
+		Headroom:     20,
+		DefaultLimit: 100_000,
+	}
+}
@@ -0,0 +1,178 @@
+package wallet
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/gagliardetto/solana-go"
+	computeBudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/rpc"
+	"trader.fun/pumpfun"
+)
+
+// Simulation is what a transaction would have done had it been sent
+type Simulation struct {
+	Signature     solana.Signature
+	Logs          []string
+	UnitsConsumed uint64
+	Err           error   // why the transaction would fail, nil if it would land
+	TokenDelta    float64 // change in ui token amount, positive on buys
+	SolDelta      float64 // change in sol balance as the simulation reports it
+}
+
+// SimulateBuy builds and signs the same transaction BuyToken would send and simulates it instead
+func (sw *SolWallet) SimulateBuy(coin *pumpfun.Coin, solAmount, slippage float64) (*Simulation, error) {
+	sw.walletLock.Lock()
+	defer sw.walletLock.Unlock()
+
+	plan, err := sw.buyPlan(coin, solAmount, slippage)
+	if err != nil {
+		return nil, err
+	}
+	return sw.simulate(plan)
+}
+
+// SimulateSell builds and signs the same transaction SellToken would send and simulates it instead
+func (sw *SolWallet) SimulateSell(coin *pumpfun.Coin, percentage, slippage float64) (*Simulation, error) {
+	sw.walletLock.Lock()
+	defer sw.walletLock.Unlock()
+
+	_, plan, err := sw.sellPlan(coin, percentage, slippage)
+	if err != nil {
+		return nil, err
+	}
+	return sw.simulate(plan)
+}
+
+// SimulateWithdrawl builds and signs the same transaction Withdrawl would send and simulates it instead
+func (sw *SolWallet) SimulateWithdrawl(address string, solAmount float64) (*Simulation, error) {
+	sw.walletLock.Lock()
+	defer sw.walletLock.Unlock()
+
+	plan, err := sw.withdrawPlan(address, solAmount)
+	if err != nil {
+		return nil, err
+	}
+	return sw.simulate(plan)
+}
+
+// simulate runs the signed transaction with signature checks and compares the wallet's accounts before and after
+func (sw *SolWallet) simulate(p *plan) (*Simulation, error) {
+	ctx := context.Background()
+
+	tx, _, err := sw.transaction(p)
+	if errors.Is(err, ErrSlippageExceeded) || errors.Is(err, ErrInsufficientFunds) {
+		// the fee estimator gave up on it, simulate anyway to get the logs
+		tx, err = sw.unbudgeted(p)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := []solana.PublicKey{sw.Wallet.PublicKey()}
+	if !p.mint.IsZero() {
+		tokenAccount, _, err := solana.FindAssociatedTokenAddress(sw.Wallet.PublicKey(), p.mint)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, tokenAccount)
+	}
+
+	before, err := sw.RpcClient.GetMultipleAccountsWithOpts(ctx, accounts, &rpc.GetMultipleAccountsOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: rpc.CommitmentProcessed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting accounts: %v", err)
+	}
+
+	result, err := sw.RpcClient.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		SigVerify:  true,
+		Commitment: rpc.CommitmentProcessed,
+		Accounts: &rpc.SimulateTransactionAccountsOpts{
+			Encoding:  solana.EncodingBase64,
+			Addresses: accounts,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error simulating transaction: %v", err)
+	}
+	if result.Value == nil {
+		return nil, errors.New("empty simulation result")
+	}
+
+	simulation := &Simulation{
+		Signature: tx.Signatures[0],
+		Logs:      result.Value.Logs,
+	}
+	if result.Value.UnitsConsumed != nil {
+		simulation.UnitsConsumed = *result.Value.UnitsConsumed
+	}
+	if result.Value.Err != nil {
+		simulation.Err = &TxError{Signature: simulation.Signature, Err: classifyTxError(result.Value.Err), Raw: result.Value.Err}
+		// a failed transaction changes nothing but the fee, and the rpc returns no accounts for it
+		return simulation, nil
+	}
+
+	if len(result.Value.Accounts) == len(accounts) && len(before.Value) == len(accounts) {
+		simulation.SolDelta = (float64(lamports(result.Value.Accounts[0])) - float64(lamports(before.Value[0]))) / float64(solana.LAMPORTS_PER_SOL)
+		if len(accounts) > 1 {
+			delta := float64(tokenAmount(result.Value.Accounts[1])) - float64(tokenAmount(before.Value[1]))
+			simulation.TokenDelta = delta / math.Pow10(pumpfun.TokenDecimals)
+		}
+	}
+
+	return simulation, nil
+}
+
+// unbudgeted signs the plan with only the maximum compute limit, for transactions the fee estimator refused
+func (sw *SolWallet) unbudgeted(p *plan) (*solana.Transaction, error) {
+	instructions := append([]solana.Instruction{
+		computeBudget.NewSetComputeUnitLimitInstruction(maxComputeUnits).Build(),
+	}, p.instructions...)
+
+	blockhash, err := sw.Blockhashes.Get(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := solana.NewTransaction(instructions, blockhash.Hash, solana.TransactionPayer(sw.Wallet.PublicKey()))
+	if err != nil {
+		return nil, fmt.Errorf("error creating transaction: %v", err)
+	}
+	if _, err := tx.Sign(sw.privateKeyGetter); err != nil {
+		return nil, fmt.Errorf("error signing transaction: %v", err)
+	}
+	return tx, nil
+}
+
+func lamports(account *rpc.Account) uint64 {
+	if account == nil {
+		return 0
+	}
+	return account.Lamports
+}
+
+// tokenAmount reads the amount of an spl token account: mint, owner, then the amount as a u64
+func tokenAmount(account *rpc.Account) uint64 {
+	if account == nil || account.Data == nil {
+		return 0
+	}
+	data := account.Data.GetBinary()
+	if len(data) < 72 {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(data[64:72])
+}
+
+// String prints the simulation the way the wallet command shows it
+func (s *Simulation) String() string {
+	status := "ok"
+	if s.Err != nil {
+		status = s.Err.Error()
+	}
+	return fmt.Sprintf("simulated %s: %s, %d compute units, %+.9f sol, %+.6f tokens", s.Signature, status, s.UnitsConsumed, s.SolDelta, s.TokenDelta)
+}
@@ -1,357 +1,511 @@
 package wallet
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
-	"os"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/gagliardetto/solana-go"
 	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
-	computeBudget "github.com/gagliardetto/solana-go/programs/compute-budget"
 	"github.com/gagliardetto/solana-go/programs/system"
 	"github.com/gagliardetto/solana-go/rpc"
+	"trader.fun/journal"
+	"trader.fun/keystore"
+	"trader.fun/ledger"
 	"trader.fun/pumpfun"
-)
-
-const (
-	historyFileName = "history.json"
+	"trader.fun/pumpfun/program"
 )
 
 type SolWallet struct {
-	Wallet          *solana.Wallet
-	RpcClient       *rpc.Client
-	PurchaseHistory map[pumpfun.Coin]float64 `json:"purchaseHistory"`
-	walletLock      sync.Mutex
+	Wallet    *solana.Wallet
+	RpcClient *rpc.Client
+	Tracker   *Tracker
+	Fees      *FeeEstimator
+	// Blockhashes can be shared by every wallet on the same rpc
+	Blockhashes *BlockhashCache
+	Retries     int // times a transaction is rebuilt after its blockhash expires
+	Ledger      *ledger.Ledger
+	Journal     *journal.Journal
+	pending     []*pendingOrder // orders Reconcile still has to settle
+	walletLock  sync.Mutex
 }
 
-func (sw *SolWallet) BuyToken(coin *pumpfun.Coin, solAmount, slippage float64) error {
+// BuyToken returns the confirmed fill, the ledger records the tokens that actually landed.
+// A transaction that wasn't seen to land or expire in time is left pending for Reconcile.
+func (sw *SolWallet) BuyToken(coin *pumpfun.Coin, solAmount, slippage float64) (*Fill, error) {
 	sw.walletLock.Lock()
 	defer sw.walletLock.Unlock()
 
-	walletAddress := sw.Wallet.PublicKey()
+	plan, err := sw.buyPlan(coin, solAmount, slippage)
+	if err != nil {
+		return nil, err
+	}
 
-	TokenAddress, _, err := solana.FindAssociatedTokenAddress(walletAddress, coin.MintAddr)
+	held := sw.held(coin.MintAddr)
+	order := journal.Event{Mint: coin.MintAddr, BondingCurve: coin.TokenBondingCurve, Side: journal.Buy}
+	sig, lastValidBlockHeight, err := sw.land(plan, func(sig solana.Signature) {
+		order.Signature = sig.String()
+		sw.record(order, journal.OrderSubmitted)
+	})
+	if errors.Is(err, ErrNotConfirmed) {
+		sw.pend(&pendingOrder{coin: *coin, order: order, sig: sig, plan: plan, held: held, lastValidBlockHeight: lastValidBlockHeight})
+		return nil, err
+	}
 	if err != nil {
-		return err
+		sw.recordFailed(order, err)
+		return nil, err
 	}
 
-	BondingCurveData, err := pumpfun.GetBondingCurveInfos(sw.RpcClient, coin.TokenBondingCurve)
+	return sw.bought(*coin, order, sig, held, plan)
+}
+
+// SellToken returns the confirmed fill, the ledger is only updated with what was actually sold.
+// A transaction that wasn't seen to land or expire in time is left pending for Reconcile.
+func (sw *SolWallet) SellToken(coin *pumpfun.Coin, percentage, slippage float64) (*Fill, error) {
+	sw.walletLock.Lock()
+	defer sw.walletLock.Unlock()
+
+	position, plan, err := sw.sellPlan(coin, percentage, slippage)
 	if err != nil {
-		return fmt.Errorf("error getting bonding curve data: %v", err)
+		return nil, err
 	}
 
-	if coin.AssociatedBondingCurve.IsZero() {
-		coin.Metadata()
+	order := journal.Event{Mint: coin.MintAddr, BondingCurve: coin.TokenBondingCurve, Side: journal.Sell}
+	sig, lastValidBlockHeight, err := sw.land(plan, func(sig solana.Signature) {
+		order.Signature = sig.String()
+		sw.record(order, journal.OrderSubmitted)
+	})
+	if errors.Is(err, ErrNotConfirmed) {
+		sw.pend(&pendingOrder{coin: *coin, order: order, sig: sig, plan: plan, held: position.Amount(), lastValidBlockHeight: lastValidBlockHeight})
+		return nil, err
+	}
+	if err != nil {
+		sw.recordFailed(order, err)
+		return nil, err
 	}
 
-	solTokenPrice := float64(float64(BondingCurveData.VirtualSolReserves/solana.LAMPORTS_PER_SOL)) / float64(BondingCurveData.VirtualTokenReserves) * 1000000
-	solTokenPrice = math.Round(solTokenPrice*1e9) / 1e9
+	return sw.sold(*coin, order, sig, position.Amount(), plan)
+}
 
-	TokenAmount := ((solAmount) / (solTokenPrice))
-	TokenAmountInInt := uint64(TokenAmount * 1000000)
-	solInWithSlippage := (solAmount) * (1 + slippage)
-	lamportsInWithSlippage := uint64(solInWithSlippage * 1000000000)
-	createATAInstruction := associatedtokenaccount.NewCreateInstruction(
-		sw.Wallet.PublicKey(),
-		sw.Wallet.PublicKey(),
-		coin.MintAddr,
-	).Build()
+// bought settles a buy that landed into the journal and the ledger
+func (sw *SolWallet) bought(coin pumpfun.Coin, order journal.Event, sig solana.Signature, held float64, p *plan) (*Fill, error) {
+	fill, err := sw.settle(sig, coin.MintAddr, held, p)
+	if err != nil {
+		sw.recordFailed(order, err)
+		return nil, err
+	}
+	order.Tokens, order.Sol = fill.TokenDelta, -fill.SolDelta
+	if fill.Estimated {
+		order.Error = "transaction unreadable, sol is the quote"
+	}
+	sw.record(order, journal.OrderConfirmed)
 
-	accountKeys := solana.AccountMetaSlice{
-		{PublicKey: solana.MustPublicKeyFromBase58("4wTV1YmiEkRvAtNtsSGPtUrqRYQMe5SKy2uB4Jjaxnjf"), IsSigner: false, IsWritable: false},
-		{PublicKey: solana.MustPublicKeyFromBase58("CebN5WGQ4jvEPvsVU4EoHEpgzq1VV7AbicfhtW4xC9iM"), IsSigner: false, IsWritable: true},
-		{PublicKey: coin.MintAddr, IsSigner: false, IsWritable: false},
-		{PublicKey: coin.TokenBondingCurve, IsSigner: false, IsWritable: true},
-		{PublicKey: coin.AssociatedBondingCurve, IsSigner: false, IsWritable: true},
-		{PublicKey: TokenAddress, IsSigner: false, IsWritable: true},
-		{PublicKey: sw.Wallet.PublicKey(), IsSigner: true, IsWritable: true},
-		{PublicKey: solana.SystemProgramID, IsSigner: false, IsWritable: false},
-		{PublicKey: solana.TokenProgramID, IsSigner: false, IsWritable: false},
-		{PublicKey: solana.SysVarRentPubkey, IsSigner: false, IsWritable: false},
-		{PublicKey: solana.MustPublicKeyFromBase58("Ce6TQqeHC9p8KetsN6JsjHK7UTZk7nasjjnr7XxXp9F1"), IsSigner: false, IsWritable: false},
-		{PublicKey: solana.MustPublicKeyFromBase58("6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"), IsSigner: false, IsWritable: false},
-	}
-
-	var buf bytes.Buffer
-	binary.Write(&buf, binary.LittleEndian, uint64(16927863322537952870)) // buy discriminatior
-	binary.Write(&buf, binary.LittleEndian, uint64(TokenAmountInInt))
-	binary.Write(&buf, binary.LittleEndian, lamportsInWithSlippage)
-	data := buf.Bytes()
-
-	BuyInstruction := solana.NewInstruction(
-		solana.MustPublicKeyFromBase58("6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"),
-		accountKeys,
-		data,
-	)
-
-	computeBudgetInstruction := computeBudget.NewSetComputeUnitPriceInstruction(uint64(250000)).Build()
-	computeBudgetInstruction2 := computeBudget.NewSetComputeUnitLimitInstruction(uint32(100000)).Build()
-
-	// Create a transaction
-	instructions := []solana.Instruction{
-		computeBudgetInstruction,
-		computeBudgetInstruction2,
-		createATAInstruction,
-		BuyInstruction,
-	}
-
-	blockHash, err := sw.RpcClient.GetRecentBlockhash(context.Background(), rpc.CommitmentFinalized)
+	sw.Ledger.Buy(coin, pumpfun.RawTokens(fill.TokenDelta), -fill.SolDelta, sig.String(), time.Now())
+	return fill, sw.Ledger.Save()
+}
+
+// sold settles a sell that landed into the journal and the ledger
+func (sw *SolWallet) sold(coin pumpfun.Coin, order journal.Event, sig solana.Signature, held float64, p *plan) (*Fill, error) {
+	fill, err := sw.settle(sig, coin.MintAddr, held, p)
 	if err != nil {
-		return fmt.Errorf("error getting recent blockhash: %v", err)
+		sw.recordFailed(order, err)
+		return nil, err
+	}
+	order.Tokens, order.Sol = -fill.TokenDelta, fill.SolDelta
+	if fill.Estimated {
+		order.Error = "transaction unreadable, sol is the quote"
 	}
+	sw.record(order, journal.OrderConfirmed)
 
-	tx, err := solana.NewTransaction(instructions, blockHash.Value.Blockhash, solana.TransactionPayer(sw.Wallet.PublicKey()))
+	position, _ := sw.Ledger.Position(coin.MintAddr)
+	realized, err := sw.Ledger.Sell(coin.MintAddr, pumpfun.RawTokens(-fill.TokenDelta), fill.SolDelta, sig.String(), time.Now())
 	if err != nil {
-		return fmt.Errorf("error creating transaction: %v", err)
+		return fill, err
 	}
+	if _, open := sw.Ledger.Position(coin.MintAddr); !open {
+		order.PnL = position.RealizedPnL + realized
+		sw.record(order, journal.PositionClosed)
+	}
+
+	return fill, sw.Ledger.Save()
+}
+
+// pendingOrder is an order whose transaction was neither seen to land nor to expire
+type pendingOrder struct {
+	coin                 pumpfun.Coin
+	order                journal.Event
+	sig                  solana.Signature
+	plan                 *plan
+	held                 float64 // tokens the ledger had before the order
+	lastValidBlockHeight uint64
+}
 
-	privateKeyGetter := func(pubKey solana.PublicKey) *solana.PrivateKey {
-		if pubKey == sw.Wallet.PublicKey() {
-			return &sw.Wallet.PrivateKey
+func (sw *SolWallet) pend(p *pendingOrder) {
+	fmt.Println("Wallet:", p.sig, "not confirmed yet, reconciling it later")
+	sw.record(p.order, journal.OrderPending)
+	sw.pending = append(sw.pending, p)
+}
+
+// Reconcile settles the pending orders whose outcome is known by now: one that landed is recorded like any other,
+// one that failed on chain or whose blockhash expired without it landing is recorded as failed
+func (sw *SolWallet) Reconcile() {
+	sw.walletLock.Lock()
+	defer sw.walletLock.Unlock()
+
+	var pending []*pendingOrder
+	for _, p := range sw.pending {
+		if !sw.reconcile(p) {
+			pending = append(pending, p)
 		}
-		return nil
 	}
+	sw.pending = pending
+}
 
-	tx.Sign(privateKeyGetter)
-
-	opts := rpc.TransactionOpts{
-		SkipPreflight:       true,
-		PreflightCommitment: rpc.CommitmentFinalized,
+// reconcile reports whether the pending order is settled
+func (sw *SolWallet) reconcile(p *pendingOrder) bool {
+	// the height is read first, a transaction still unseen after that passed lastValidBlockHeight can't land any more
+	height, heightErr := sw.RpcClient.GetBlockHeight(context.Background(), rpc.CommitmentConfirmed)
+	statuses, err := sw.RpcClient.GetSignatureStatuses(context.Background(), true, p.sig)
+	if err != nil || len(statuses.Value) == 0 {
+		return false
 	}
 
-	// txID from this function
-	if _, err := sw.RpcClient.SendTransactionWithOpts(context.Background(), tx, opts); err != nil {
-		return fmt.Errorf("error sending transaction: %v", err)
-	} else {
-		if amount, ok := sw.PurchaseHistory[*coin]; ok {
-			sw.PurchaseHistory[*coin] = amount + TokenAmount
-		} else {
-			sw.PurchaseHistory[*coin] = TokenAmount
+	if statuses.Value[0] == nil {
+		if heightErr != nil || height <= p.lastValidBlockHeight {
+			return false
 		}
-		return sw.SaveHistory()
+		sw.recordFailed(p.order, ErrBlockhashExpired)
+		return true
+	}
+
+	done, err := settled(p.sig, statuses.Value[0])
+	if !done {
+		return false
+	}
+	if err != nil {
+		sw.recordFailed(p.order, err)
+		return true
+	}
+
+	settle := sw.bought
+	if p.order.Side == journal.Sell {
+		settle = sw.sold
 	}
+	if _, err := settle(p.coin, p.order, p.sig, p.held, p.plan); err != nil {
+		fmt.Println("Wallet: error reconciling", p.sig, err)
+	}
+	return true
 }
 
-func (sw *SolWallet) SellToken(coin *pumpfun.Coin, percentage, slippage float64) error {
-	sw.walletLock.Lock()
-	defer sw.walletLock.Unlock()
+// plan is an operation's instructions before the compute budget is added
+type plan struct {
+	instructions []solana.Instruction
+	writable     []solana.PublicKey // the contested accounts the priority fee is sampled on
+	mint         solana.PublicKey   // the token whose balance the operation changes, zero for sol transfers
+	quote        Fill               // the token and sol change the plan was priced at
+}
 
+func (sw *SolWallet) buyPlan(coin *pumpfun.Coin, solAmount, slippage float64) (*plan, error) {
 	walletAddress := sw.Wallet.PublicKey()
 
-	if percentage > 100 || percentage < 0 {
-		return errors.New("sell percentage must be between 0-100")
+	if err := coin.Derive(); err != nil {
+		return nil, err
 	}
 
-	totalHoldings, holdingToken := sw.PurchaseHistory[*coin]
-	if !holdingToken {
-		return errors.New("you must be holding this token to sell it")
+	BondingCurveData, err := pumpfun.GetBondingCurveInfos(context.Background(), sw.RpcClient, coin.TokenBondingCurve)
+	if err != nil {
+		return nil, fmt.Errorf("error getting bonding curve data: %v", err)
 	}
 
-	BondingCurveData, err := pumpfun.GetBondingCurveInfos(sw.RpcClient, coin.TokenBondingCurve)
+	lamportsIn := uint64(solAmount * float64(solana.LAMPORTS_PER_SOL))
+	TokenAmountInInt := BondingCurveData.QuoteBuy(lamportsIn)
+	if TokenAmountInInt == 0 {
+		return nil, errors.New("buy is too small to get any tokens")
+	}
+	cost, err := BondingCurveData.BuyCost(TokenAmountInInt)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	lamportsInWithSlippage := uint64(float64(cost) * (1 + slippage))
+	createATAInstruction := associatedtokenaccount.NewCreateInstruction(
+		sw.Wallet.PublicKey(),
+		sw.Wallet.PublicKey(),
+		coin.MintAddr,
+	).Build()
 
-	TokenAddress, _, err := solana.FindAssociatedTokenAddress(walletAddress, coin.MintAddr)
+	BuyInstruction, err := program.Buy(coin.MintAddr, walletAddress, program.FeeRecipient, TokenAmountInInt, lamportsInWithSlippage)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if coin.AssociatedBondingCurve.IsZero() {
-		coin.Metadata()
-	}
+	return &plan{
+		instructions: []solana.Instruction{createATAInstruction, BuyInstruction},
+		writable:     []solana.PublicKey{coin.TokenBondingCurve, coin.AssociatedBondingCurve},
+		mint:         coin.MintAddr,
+		quote: Fill{
+			TokenDelta: pumpfun.WholeTokens(TokenAmountInInt),
+			SolDelta:   -solAmount,
+		},
+	}, nil
+}
 
-	sellAmount := totalHoldings * (percentage / 100)
+// sellPlan also returns the position being sold from
+func (sw *SolWallet) sellPlan(coin *pumpfun.Coin, percentage, slippage float64) (*ledger.Position, *plan, error) {
+	walletAddress := sw.Wallet.PublicKey()
 
-	solTokenPrice := float64(float64(BondingCurveData.VirtualSolReserves/solana.LAMPORTS_PER_SOL)) / float64(BondingCurveData.VirtualTokenReserves) * 1000000
-	solTokenPrice = math.Round(solTokenPrice*1e9) / 1e9
+	if percentage > 100 || percentage < 0 {
+		return nil, nil, errors.New("sell percentage must be between 0-100")
+	}
 
-	TokenAmountInInt := uint64(sellAmount) * 1000000
-	solOut := (sellAmount * solTokenPrice)
-	solOutWithSlippage := solOut * (1 - slippage)
-	lamportsOutWithSlippage := uint64(solOutWithSlippage * 1000000000)
+	position, holdingToken := sw.Ledger.Position(coin.MintAddr)
+	if !holdingToken {
+		return nil, nil, ledger.ErrNotHolding
+	}
 
-	accountKeys := solana.AccountMetaSlice{
-		{PublicKey: solana.MustPublicKeyFromBase58("4wTV1YmiEkRvAtNtsSGPtUrqRYQMe5SKy2uB4Jjaxnjf"), IsSigner: false, IsWritable: false},
-		{PublicKey: solana.MustPublicKeyFromBase58("CebN5WGQ4jvEPvsVU4EoHEpgzq1VV7AbicfhtW4xC9iM"), IsSigner: false, IsWritable: true},
-		{PublicKey: coin.MintAddr, IsSigner: false, IsWritable: false},
-		{PublicKey: coin.TokenBondingCurve, IsSigner: false, IsWritable: true},
-		{PublicKey: coin.AssociatedBondingCurve, IsSigner: false, IsWritable: true},
-		{PublicKey: TokenAddress, IsSigner: false, IsWritable: true},
-		{PublicKey: sw.Wallet.PublicKey(), IsSigner: true, IsWritable: true},
-		{PublicKey: solana.SystemProgramID, IsSigner: false, IsWritable: false},
-		{PublicKey: solana.MustPublicKeyFromBase58("ATokenGPvbdGVxr1b2hvZbsiqW5xWH25efTNsLJA8knL"), IsSigner: false, IsWritable: false},
-		{PublicKey: solana.TokenProgramID, IsSigner: false, IsWritable: false},
-		{PublicKey: solana.MustPublicKeyFromBase58("Ce6TQqeHC9p8KetsN6JsjHK7UTZk7nasjjnr7XxXp9F1"), IsSigner: false, IsWritable: false},
-		{PublicKey: solana.MustPublicKeyFromBase58("6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"), IsSigner: false, IsWritable: false},
+	if err := coin.Derive(); err != nil {
+		return nil, nil, err
 	}
 
-	var buf bytes.Buffer
-	binary.Write(&buf, binary.LittleEndian, uint64(12502976635542562355))
-	binary.Write(&buf, binary.LittleEndian, uint64(TokenAmountInInt))
-	binary.Write(&buf, binary.LittleEndian, lamportsOutWithSlippage)
-	data := buf.Bytes()
+	BondingCurveData, err := pumpfun.GetBondingCurveInfos(context.Background(), sw.RpcClient, coin.TokenBondingCurve)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	SellInstruction := solana.NewInstruction(
-		solana.MustPublicKeyFromBase58("6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"),
-		accountKeys,
-		data,
-	)
+	// everything held is sold exactly, a float share of it can come out a raw unit short and leave dust
+	TokenAmountInInt := position.Tokens()
+	if percentage < 100 {
+		TokenAmountInInt = uint64(float64(TokenAmountInInt) * percentage / 100)
+	}
+	lamportsOutWithSlippage := uint64(float64(BondingCurveData.QuoteSell(TokenAmountInInt)) * (1 - slippage))
 
-	instructions := []solana.Instruction{
-		SellInstruction,
+	SellInstruction, err := program.Sell(coin.MintAddr, walletAddress, program.FeeRecipient, TokenAmountInInt, lamportsOutWithSlippage)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	blockHash, err := sw.RpcClient.GetRecentBlockhash(context.Background(), rpc.CommitmentFinalized)
+	return position, &plan{
+		instructions: []solana.Instruction{SellInstruction},
+		writable:     []solana.PublicKey{coin.TokenBondingCurve, coin.AssociatedBondingCurve},
+		mint:         coin.MintAddr,
+		quote: Fill{
+			TokenDelta: -pumpfun.WholeTokens(TokenAmountInInt),
+			SolDelta:   float64(BondingCurveData.QuoteSell(TokenAmountInInt)) / float64(solana.LAMPORTS_PER_SOL),
+		},
+	}, nil
+}
+
+// transaction prices and signs the plan against the cached blockhash
+func (sw *SolWallet) transaction(p *plan) (*solana.Transaction, *Blockhash, error) {
+	walletAddress := sw.Wallet.PublicKey()
+
+	instructions, err := sw.Fees.Budget(context.Background(), p.instructions, p.writable, walletAddress, sw.privateKeyGetter)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	if _, err := solana.NewTransaction(instructions, blockHash.Value.Blockhash, solana.TransactionPayer(sw.Wallet.PublicKey())); err != nil {
-		return err
+	blockhash, err := sw.Blockhashes.Get(context.Background())
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if totalHoldings-sellAmount <= 0 {
-		delete(sw.PurchaseHistory, *coin)
-	} else {
-		sw.PurchaseHistory[*coin] = totalHoldings - sellAmount
+	tx, err := solana.NewTransaction(instructions, blockhash.Hash, solana.TransactionPayer(walletAddress))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating transaction: %v", err)
 	}
 
-	return sw.SaveHistory()
+	if _, err := tx.Sign(sw.privateKeyGetter); err != nil {
+		return nil, nil, fmt.Errorf("error signing transaction: %v", err)
+	}
+	return tx, blockhash, nil
 }
 
-func (sw *SolWallet) SellAll(slippage float64) error {
-	for coin, _ := range sw.PurchaseHistory {
-		if err := sw.SellToken(&coin, 100, slippage); err != nil { // sell 100% of every token
-			return err
+// land sends the plan and waits for it to confirm. A transaction whose blockhash expires can no longer land,
+// so it's rebuilt with a fresh blockhash up to Retries times. submitted is called with every signature sent.
+// It also returns the lastValidBlockHeight of the transaction sent last.
+func (sw *SolWallet) land(p *plan, submitted func(solana.Signature)) (solana.Signature, uint64, error) {
+	for attempt := 0; attempt <= sw.Retries; attempt++ {
+		if attempt > 0 {
+			fmt.Printf("Wallet: blockhash expired, rebuilding transaction (retry %d of %d)\n", attempt, sw.Retries)
 		}
-	}
-	return nil
-}
 
-func (sw *SolWallet) Withdrawl(address string, solAmount float64) error {
+		tx, blockhash, err := sw.transaction(p)
+		if err != nil {
+			return solana.Signature{}, 0, err
+		}
 
-	if solBalance, err := sw.SolBalance(); err != nil {
-		return err
-	} else if solAmount >= solBalance {
-		return errors.New("not enough sol in wallet to complete this transaction")
-	}
+		sig, err := sw.Tracker.Send(tx)
+		if err != nil {
+			return solana.Signature{}, 0, fmt.Errorf("error sending transaction: %v", err)
+		}
+		if submitted != nil {
+			submitted(sig)
+		}
 
-	lamports := uint64(solAmount * float64(solana.LAMPORTS_PER_SOL))
+		if err := sw.Tracker.Confirm(tx, blockhash.LastValidBlockHeight); !errors.Is(err, ErrBlockhashExpired) {
+			return sig, blockhash.LastValidBlockHeight, err
+		}
+	}
+	return solana.Signature{}, 0, ErrBlockhashExpired
+}
 
-	recent, err := sw.RpcClient.GetLatestBlockhash(context.TODO(), rpc.CommitmentFinalized)
-	if err != nil {
-		return err
+// settle reads what a landed transaction did to the wallet. The transaction has moved tokens and sol by now,
+// so it's only an error when it failed on chain: when it can't be read the token change is taken from the
+// token account against held, what the ledger had before, or from the plan's quote if that can't be read either.
+func (sw *SolWallet) settle(sig solana.Signature, mint solana.PublicKey, held float64, p *plan) (*Fill, error) {
+	fill, err := sw.Tracker.AwaitFill(sig, sw.Wallet.PublicKey(), mint)
+	var txErr *TxError
+	if err == nil || errors.As(err, &txErr) {
+		return fill, err
 	}
+	fmt.Println("Wallet: error reading", sig, err, "- reconciling from the token account")
 
-	tx, err := solana.NewTransaction(
-		[]solana.Instruction{
-			system.NewTransferInstruction(
-				lamports,
-				sw.Wallet.PublicKey(),
-				solana.MPK(address),
-			).Build(),
-		},
-		recent.Value.Blockhash,
-		solana.TransactionPayer(sw.Wallet.PublicKey()),
-	)
+	fill = &Fill{Signature: sig, TokenDelta: p.quote.TokenDelta, SolDelta: p.quote.SolDelta, Estimated: true}
+	balance, err := sw.TokenBalance(mint)
 	if err != nil {
-		return err
+		fmt.Println("Wallet: error reading token account, using the quote:", err)
+		return fill, nil
 	}
-
-	// Sign transaction
-	if _, err := tx.Sign(
-		func(key solana.PublicKey) *solana.PrivateKey {
-			if sw.Wallet.PublicKey().Equals(key) {
-				return &sw.Wallet.PrivateKey
-			}
-			return nil
-		},
-	); err != nil {
-		return err
+	// a lagging rpc can still show the balance from before, the quote is closer than no change at all
+	if delta := balance - held; delta != 0 && (delta > 0) == (p.quote.TokenDelta > 0) {
+		fill.TokenDelta = delta
 	}
+	return fill, nil
+}
 
-	// send transaction
-	if _, err := sw.RpcClient.SendTransaction(
-		context.Background(),
-		tx,
-	); err != nil {
-		return err
+// held is how many tokens of mint the ledger has
+func (sw *SolWallet) held(mint solana.PublicKey) float64 {
+	if position, ok := sw.Ledger.Position(mint); ok {
+		return position.Amount()
 	}
-
-	return nil
+	return 0
 }
 
-func (sw *SolWallet) SolBalance() (float64, error) {
-	balanceResult, err := sw.RpcClient.GetBalance(context.Background(), sw.Wallet.PublicKey(), rpc.CommitmentConfirmed)
+// TokenBalance is the wallet's balance of mint in whole tokens, zero when it has no token account
+func (sw *SolWallet) TokenBalance(mint solana.PublicKey) (float64, error) {
+	account, _, err := solana.FindAssociatedTokenAddress(sw.Wallet.PublicKey(), mint)
 	if err != nil {
 		return 0, err
 	}
-
-	return float64(balanceResult.Value) / float64(solana.LAMPORTS_PER_SOL), nil
-}
-
-func (sw *SolWallet) SaveHistory() error {
-	data, err := json.MarshalIndent(sw.PurchaseHistory, "", "  ") // MarshalIndent for pretty-printed JSON
+	balance, err := sw.RpcClient.GetTokenAccountBalance(context.Background(), account, rpc.CommitmentConfirmed)
+	if errors.Is(err, rpc.ErrNotFound) {
+		return 0, nil
+	}
 	if err != nil {
-		return fmt.Errorf("error marshalling map to JSON: %v", err)
+		return 0, err
 	}
-
-	file, err := os.OpenFile(historyFileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	amount, err := strconv.ParseUint(balance.Value.Amount, 10, 64)
 	if err != nil {
-		return fmt.Errorf("error creating file: %v", err)
+		return 0, err
 	}
-	defer file.Close()
+	return float64(amount) / math.Pow10(int(balance.Value.Decimals)), nil
+}
 
-	// Write the JSON data to the file
-	_, err = file.Write(data)
-	if err != nil {
-		return fmt.Errorf("error writing to file: %v", err)
+func (sw *SolWallet) record(e journal.Event, eventType journal.EventType) {
+	e.Type = eventType
+	if err := sw.Journal.Record(e); err != nil {
+		fmt.Println("Journal:", err)
 	}
+}
+
+func (sw *SolWallet) recordFailed(e journal.Event, err error) {
+	e.Error = err.Error()
+	sw.record(e, journal.OrderFailed)
+}
 
+func (sw *SolWallet) privateKeyGetter(pubKey solana.PublicKey) *solana.PrivateKey {
+	if pubKey == sw.Wallet.PublicKey() {
+		return &sw.Wallet.PrivateKey
+	}
 	return nil
 }
 
-func (sw *SolWallet) LoadHistory() error {
+// SellAll tries to sell 100% of every position, one that fails doesn't stop the rest
+func (sw *SolWallet) SellAll(slippage float64) error {
+	var errs []error
+	for _, position := range sw.Ledger.Open() {
+		coin := position.Coin()
+		if _, err := sw.SellToken(&coin, 100, slippage); err != nil {
+			errs = append(errs, fmt.Errorf("error selling %s: %v", position.Mint, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (sw *SolWallet) Withdrawl(address string, solAmount float64) error {
+	sw.walletLock.Lock()
+	defer sw.walletLock.Unlock()
+
+	if solBalance, err := sw.SolBalance(); err != nil {
+		return err
+	} else if solAmount >= solBalance {
+		return errors.New("not enough sol in wallet to complete this transaction")
+	}
 
-	// Open the file
-	file, err := os.Open(historyFileName)
+	plan, err := sw.withdrawPlan(address, solAmount)
 	if err != nil {
-		return fmt.Errorf("error opening file: %v", err)
+		return err
 	}
-	defer file.Close()
 
-	// Decode the JSON data into a map
-	var m map[pumpfun.Coin]float64
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&m)
+	_, _, err = sw.land(plan, nil)
+	return err
+}
+
+func (sw *SolWallet) withdrawPlan(address string, solAmount float64) (*plan, error) {
+	recipient, err := solana.PublicKeyFromBase58(address)
 	if err != nil {
-		return fmt.Errorf("error decoding JSON: %v", err)
+		return nil, fmt.Errorf("invalid address %s: %v", address, err)
 	}
 
-	sw.PurchaseHistory = m
+	lamports := uint64(solAmount * float64(solana.LAMPORTS_PER_SOL))
 
-	return nil
+	return &plan{
+		instructions: []solana.Instruction{
+			system.NewTransferInstruction(
+				lamports,
+				sw.Wallet.PublicKey(),
+				recipient,
+			).Build(),
+		},
+		writable: []solana.PublicKey{sw.Wallet.PublicKey(), recipient},
+	}, nil
 }
 
-func New(RpcClient *rpc.Client, privateKey string) *SolWallet {
-	var wallet *solana.Wallet
-	if len(privateKey) > 0 {
-		wallet, _ = solana.WalletFromPrivateKeyBase58(privateKey)
-	} else {
-		wallet = solana.NewWallet()
+func (sw *SolWallet) SolBalance() (float64, error) {
+	balanceResult, err := sw.RpcClient.GetBalance(context.Background(), sw.Wallet.PublicKey(), rpc.CommitmentConfirmed)
+	if err != nil {
+		return 0, err
 	}
 
-	sw := &SolWallet{
-		Wallet: wallet,
+	return float64(balanceResult.Value) / float64(solana.LAMPORTS_PER_SOL), nil
+}
+
+// New opens the wallet of the named keystore key, with its positions in the default ledger
+func New(RpcClient *rpc.Client, ks *keystore.Keystore, name, passphrase string) (*SolWallet, error) {
+	privateKey, err := ks.Unlock(name, passphrase)
+	if err != nil {
+		return nil, err
 	}
-	sw.LoadHistory()
+	return NewWithLedger(RpcClient, privateKey, ledger.FileName)
+}
 
-	return sw
+// NewWithLedger opens the wallet of privateKey with its positions kept at ledgerPath, every wallet needs its own ledger
+func NewWithLedger(RpcClient *rpc.Client, privateKey solana.PrivateKey, ledgerPath string) (*SolWallet, error) {
+	positions, err := ledger.Load(ledgerPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SolWallet{
+		Wallet:      &solana.Wallet{PrivateKey: privateKey},
+		RpcClient:   RpcClient,
+		Tracker:     NewTracker(RpcClient),
+		Fees:        NewFeeEstimator(RpcClient),
+		Blockhashes: NewBlockhashCache(RpcClient),
+		Retries:     2,
+		Ledger:      positions,
+	}, nil
 }